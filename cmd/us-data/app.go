@@ -2,16 +2,19 @@ package main
 
 import (
 	"us-data/internal/app"
+	"us-data/internal/crawl"
 	"us-data/internal/provider"
 )
 
 // App holds the application's top-level dependencies.
 type App struct {
-	Config *app.Config
-	DP     *provider.PolygonProvider
+	Config   *app.Config
+	DP       *provider.PolygonProvider
+	Progress crawl.ProgressStore
 }
 
-// InitializeApp wires and returns App. Caller must call a.DP.Close() when done.
+// InitializeApp wires and returns App. Caller must call a.DP.Close() and
+// a.Progress.Close() when done.
 func InitializeApp() (*App, error) {
 	app.InitLogger()
 
@@ -19,13 +22,19 @@ func InitializeApp() (*App, error) {
 	if err != nil {
 		return nil, err
 	}
+	rl := app.ProvideRateLimiter(cfg)
+	details := app.ProvideTickerDetails(cfg)
 	ps, err := app.ProvidePacketSaver(cfg)
 	if err != nil {
 		return nil, err
 	}
-	dp, err := app.ProvidePolygonProvider(cfg, ps)
+	dp, err := app.ProvidePolygonProvider(cfg, ps, rl, details)
 	if err != nil {
 		return nil, err
 	}
-	return &App{Config: cfg, DP: dp}, nil
+	progress, err := app.ProvideProgressStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &App{Config: cfg, DP: dp, Progress: progress}, nil
 }
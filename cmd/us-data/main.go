@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"us-data/internal/app"
+	"us-data/internal/fsutil"
 )
 
 func main() {
@@ -14,15 +15,36 @@ func main() {
 		os.Exit(1)
 	}
 	defer a.DP.Close()
+	defer a.Progress.Close()
 
 	defer a.Config.ApplyLogger()() // apply level + format + file; defer closes log file
 	slog.Info("provider", "name", a.DP.GetName(), "workers", len(a.Config.API.Keys))
 
-	targets, err := app.ResolveTargets(a.Config)
+	// Nothing writes yet: any temp file is what a crash left of an atomic write.
+	if n, err := fsutil.RemoveTemp(a.Config.Data.Dir); err != nil {
+		slog.Warn("temp file cleanup incomplete", "removed", n, "error", err)
+	} else if n > 0 {
+		slog.Info("removed temp files of interrupted writes", "count", n)
+	}
+
+	ctx, stop := app.SignalContext()
+	defer stop()
+
+	universe, err := app.NewUniverse(a.Config, a.DP.Crawler)
+	if err == nil {
+		err = universe.Refresh(ctx) // fail fast: later refreshes fall back to this universe
+	}
 	if err != nil {
 		slog.Error("bootstrap failed", "error", err)
 		os.Exit(1)
 	}
+	var renames *app.Renames
+	if a.Config.Data.TrackRenames {
+		if renames, err = app.NewRenames(a.Config, a.Progress, a.DP.Crawler); err != nil {
+			slog.Error("bootstrap failed", "error", err)
+			os.Exit(1)
+		}
+	}
 
-	app.Run(a.Config, a.DP, targets)
+	app.Run(ctx, a.Config, a.DP, a.Progress, universe, renames)
 }
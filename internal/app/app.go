@@ -2,9 +2,11 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,29 +17,70 @@ import (
 //
 // Responsibility: schedule + OS signal handling only.
 // It has no knowledge of tickers, API keys, or crawl internals —
-// those are encapsulated in crawl.Runner.
-func Run(cfg *Config, fetcher crawl.BarFetcher, targets []crawl.Job) {
+// those are encapsulated in crawl.Runner and Universe. universe must have been
+// refreshed once; it is refreshed again before every later cycle. renames,
+// when not nil, applies ticker symbol changes to every cycle's targets.
+//
+// Run returns once ctx is cancelled (see SignalContext), after the current
+// cycle has finished its in-flight jobs.
+func Run(ctx context.Context, cfg *Config, fetcher crawl.BarFetcher, progress crawl.ProgressStore, universe *Universe, renames *Renames) {
 	progressUpdates := make(chan crawl.ProgressUpdate, 256)
-	go crawl.RunProgressWriter(cfg.ProgressPath(), progressUpdates)
-	defer close(progressUpdates) // signals RunProgressWriter to drain and exit
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		crawl.RunProgressWriter(progress, progressUpdates)
+	}()
+	defer func() {
+		close(progressUpdates) // signals RunProgressWriter to drain and exit
+		<-writerDone           // the store must not be closed under a pending write
+	}()
 
 	runner := &crawl.Runner{
 		Fetcher:         fetcher,
 		APIKeys:         cfg.API.Keys,
-		Targets:         targets,
 		SaveBaseDir:     cfg.SaveBaseDir(),
-		ProgressPath:    cfg.ProgressPath(),
+		Progress:        progress,
 		ProgressUpdates: progressUpdates,
 		BackfillYears:   cfg.Data.BackfillYears,
+		MaxAttempts:     cfg.Retry.MaxAttempts,
+		RetryBaseDelay:  time.Duration(cfg.Retry.BaseDelaySec) * time.Second,
+		RefetchOnSplit:  cfg.Data.RefetchOnSplit,
+		Partition:       cfg.Partition(),
+		MinFreeBytes:    uint64(cfg.Data.MinFreeDiskMB) << 20,
+		Empty: crawl.EmptyPolicy{
+			Mode:           crawl.EmptyMode(strings.ToLower(cfg.Data.EmptyRange)),
+			MaxTradingDays: cfg.Data.EmptyMaxTradingDays,
+		},
+	}
+	for _, a := range cfg.EnabledAssets() {
+		if a.CorporateActions {
+			runner.ActionClasses = append(runner.ActionClasses, crawl.AssetClass(a.Class))
+		}
+	}
+	if cfg.Data.SplitJobs {
+		if cp, ok := fetcher.(crawl.ChunkPlanner); ok {
+			runner.ChunkDays = cp.MaxDaysPerChunk()
+		}
 	}
 
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
-	defer signal.Stop(signals)
-
-	for {
-		exiting := trigger(runner, signals)
-		if exiting {
+	for first := true; ; first = false {
+		if !first {
+			// Classes that fail to resolve keep their previous tickers, so
+			// after a first success only a shutdown fails the refresh.
+			if err := universe.Refresh(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				slog.Error("universe refresh failed", "err", err)
+			}
+		}
+		targets := universe.Targets()
+		if renames != nil {
+			targets = renames.Apply(ctx, universe, targets)
+		}
+		migrateTickerDirs(targets)
+		runner.Targets = targets
+		if exiting := trigger(ctx, runner); exiting {
 			return
 		}
 		nextRun := nextCrawlRunTime(cfg)
@@ -53,29 +96,39 @@ func Run(cfg *Config, fetcher crawl.BarFetcher, targets []crawl.Job) {
 		timer := time.NewTimer(waitDur)
 		select {
 		case <-timer.C:
-		case sig := <-signals:
+		case <-ctx.Done():
 			timer.Stop()
-			slog.Info("scheduler: shutting down", "signal", sig)
+			slog.Info("scheduler: shutting down", "cause", context.Cause(ctx))
 			return
 		}
 	}
 }
 
-// trigger starts one crawl run and blocks until it finishes or a signal arrives.
+// trigger starts one crawl run and blocks until it finishes; a cancelled
+// ctx makes the run finish its in-flight jobs and stop early.
 // Returns true if the process should exit.
-func trigger(runner *crawl.Runner, signals <-chan os.Signal) (shouldExit bool) {
-	ctx, cancel := context.WithCancel(context.Background())
-	doneCh := runner.Run(ctx)
+func trigger(ctx context.Context, runner *crawl.Runner) (shouldExit bool) {
+	<-runner.Run(ctx)
+	return ctx.Err() != nil
+}
 
-	select {
-	case <-doneCh:
-		cancel()
-		return false
-	case sig := <-signals:
-		slog.Info("cycle: signal received, finishing current jobs", "signal", sig)
-		cancel()
-		<-doneCh
-		return true
+// SignalContext returns a context cancelled, with the signal as its cause,
+// on the first SIGINT or SIGTERM. stop releases the signal handler.
+func SignalContext() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			slog.Info("signal received, finishing current jobs", "signal", sig)
+			cancel(fmt.Errorf("signal %v", sig))
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(context.Canceled)
 	}
 }
 
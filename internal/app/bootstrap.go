@@ -1,52 +1,264 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"us-data/internal/crawl"
+	"us-data/internal/fsutil"
+	"us-data/internal/membership"
 	"us-data/internal/provider/polygon"
 )
 
-// ResolveTargets resolves tickers for all enabled asset classes and returns
-// a flat list of crawl Jobs ready to be handed to Run.
-// It also creates the root data directory if it doesn't exist.
-func ResolveTargets(cfg *Config) ([]crawl.Job, error) {
+// maxUniverseChanges caps the change history kept in .universe.json.
+const maxUniverseChanges = 500
+
+// UniverseChange is the membership diff of one asset class between two cycles.
+type UniverseChange struct {
+	At      time.Time `json:"at"`
+	Class   string    `json:"class"`
+	Added   []string  `json:"added,omitempty"`
+	Removed []string  `json:"removed,omitempty"`
+}
+
+// universeFile is the persisted form of a Universe (.universe.json).
+type universeFile struct {
+	ResolvedAt time.Time           `json:"resolved_at"`
+	Tickers    map[string][]string `json:"tickers"` // class → tickers
+	Changes    []UniverseChange    `json:"changes,omitempty"`
+}
+
+// Universe is the set of tickers crawled per asset class. Refresh re-resolves
+// it from config (index groups, explicit tickers) at the start of each cycle,
+// so index membership changes are picked up while the process keeps running.
+//
+// The last resolved universe is persisted, so a failed refresh — also the
+// first one after a restart — falls back to it instead of stopping the crawl.
+//
+// Every loaded index group is also recorded in the membership history. A
+// ticker removed from a group stays in the universe for
+// data.membershipGraceDays, so its data runs up to (and past) the removal.
+type Universe struct {
+	cfg     *Config
+	api     *polygon.Crawler // resolves groups and validates tickers
+	path    string
+	last    universeFile
+	members *membership.Store
+	added   map[string][]string // class → tickers added by the last Refresh
+}
+
+// NewUniverse returns the universe of cfg, seeded with the persisted one,
+// resolved through api. It also creates the root data directory if it
+// doesn't exist.
+func NewUniverse(cfg *Config, api *polygon.Crawler) (*Universe, error) {
+	if err := os.MkdirAll(cfg.Data.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create data dir %q: %w", cfg.Data.Dir, err)
+	}
+	slog.Info("storage configured", "dir", cfg.SaveBaseDir(), "format", cfg.Data.Format)
+
+	u := &Universe{cfg: cfg, api: api, path: cfg.UniversePath(), members: membership.NewStore(cfg.MembershipDir())}
+	data, err := os.ReadFile(u.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("read %s: %w", u.path, err)
+	default:
+		if err := json.Unmarshal(data, &u.last); err != nil {
+			// Only the fallback is lost: the next refresh rewrites the file.
+			slog.Warn("universe file unreadable, ignored", "path", u.path, "err", err)
+			u.last = universeFile{}
+		}
+	}
+	return u, nil
+}
+
+// Refresh resolves tickers for all enabled asset classes. A class that fails
+// to resolve (or resolves to nothing where it had tickers before) keeps its
+// previous tickers; the error is returned only when there are none to keep.
+// Added and removed tickers are logged and recorded in .universe.json.
+//
+// Cancelling ctx aborts the refresh with ctx's error and keeps the previous
+// universe untouched.
+func (u *Universe) Refresh(ctx context.Context) error {
 	apiKey := ""
-	if len(cfg.API.Keys) > 0 {
-		apiKey = cfg.API.Keys[0]
+	if len(u.cfg.API.Keys) > 0 {
+		apiKey = u.cfg.API.Keys[0]
 	}
 
-	byClass := make(map[crawl.AssetClass][]string)
-	for _, asset := range cfg.EnabledAssets() {
-		class := crawl.AssetClass(asset.Class)
+	now := time.Now().UTC()
+	next := universeFile{ResolvedAt: now, Tickers: make(map[string][]string), Changes: u.last.Changes}
+	u.added = make(map[string][]string)
+	for _, asset := range u.cfg.EnabledAssets() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		slog.Info("resolving tickers",
 			"class", asset.Class, "groups", asset.Groups, "explicit", len(asset.Tickers))
 
-		syms, err := polygon.ResolveAssetTickers(apiKey, polygon.AssetTickerSpec{
+		prev, hadPrev := u.last.Tickers[asset.Class]
+		syms, err := u.api.ResolveAssetTickers(ctx, apiKey, polygon.AssetTickerSpec{
 			Class:    asset.Class,
 			Groups:   asset.Groups,
 			Tickers:  asset.Tickers,
 			Validate: asset.Validate,
+			OnGroup: func(group string, tickers []string) {
+				u.recordMembership(now, group, tickers)
+			},
 		})
+		if err == nil {
+			syms = u.withGraceMembers(now, asset, syms)
+		}
+		if err == nil && len(syms) == 0 && len(prev) > 0 {
+			err = errors.New("no tickers resolved")
+		}
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err() // shutting down: not a failure of the class
+		}
 		if err != nil {
-			return nil, fmt.Errorf("resolve %s tickers: %w", asset.Class, err)
+			if !hadPrev {
+				return fmt.Errorf("resolve %s tickers: %w", asset.Class, err)
+			}
+			slog.Warn("ticker refresh failed, keeping previous universe",
+				"class", asset.Class, "count", len(prev), "resolved_at", u.last.ResolvedAt.Format(time.RFC3339), "err", err)
+			next.Tickers[asset.Class] = prev
+			continue
+		}
+		next.Tickers[asset.Class] = syms
+		slog.Info("tickers resolved", "class", asset.Class, "count", len(syms))
+
+		if !hadPrev {
+			continue // first resolution of the class: nothing to diff against
+		}
+		if c := diffUniverse(now, asset.Class, prev, syms); len(c.Added)+len(c.Removed) > 0 {
+			slog.Info("universe changed", "class", c.Class,
+				"added", c.Added, "removed", c.Removed)
+			next.Changes = append(next.Changes, c)
+			u.added[asset.Class] = c.Added
 		}
-		byClass[class] = syms
-		slog.Info("tickers resolved", "class", class, "count", len(syms))
 	}
+	if n := len(next.Changes); n > maxUniverseChanges {
+		next.Changes = next.Changes[n-maxUniverseChanges:]
+	}
+	u.last = next
+
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err == nil {
+		err = fsutil.WriteFileAtomic(u.path, data, 0o644)
+	}
+	if err != nil {
+		// The in-memory universe is current; only the restart fallback is stale.
+		slog.Warn("universe file write failed", "path", u.path, "err", err)
+	}
+	return nil
+}
 
+// recordMembership writes today's snapshot of group. A failure is logged
+// only: it loses history, not data, and must not stop the crawl.
+func (u *Universe) recordMembership(now time.Time, group string, tickers []string) {
+	norm := make([]string, 0, len(tickers))
+	for _, t := range tickers {
+		if t = strings.ToUpper(strings.TrimSpace(t)); t != "" {
+			norm = append(norm, t)
+		}
+	}
+	c, err := u.members.Record(group, now, norm)
+	if err != nil {
+		slog.Warn("index membership not recorded", "group", group, "err", err)
+		return
+	}
+	if !c.First && len(c.Added)+len(c.Removed) > 0 {
+		slog.Info("index membership changed", "group", group, "added", c.Added, "removed", c.Removed)
+	}
+}
+
+// withGraceMembers adds to syms the tickers removed from one of asset's
+// groups within the grace period, and returns the sorted result.
+func (u *Universe) withGraceMembers(now time.Time, asset AssetConfig, syms []string) []string {
+	grace := u.cfg.Data.MembershipGraceDays
+	if grace <= 0 {
+		slices.Sort(syms)
+		return syms
+	}
+	since := now.AddDate(0, 0, -grace)
+	for _, group := range asset.Groups {
+		group = strings.ToLower(strings.TrimSpace(group))
+		if group == "" || group == "all" {
+			continue
+		}
+		h, err := u.members.Load(group)
+		if err != nil {
+			slog.Warn("index membership unreadable, no grace members", "group", group, "err", err)
+			continue
+		}
+		var kept []string
+		for _, t := range h.RemovedSince(since) {
+			if !slices.Contains(syms, t) {
+				kept = append(kept, t)
+				syms = append(syms, t)
+			}
+		}
+		if len(kept) > 0 {
+			slog.Info("removed index members kept during grace period",
+				"group", group, "tickers", kept, "grace_days", grace)
+		}
+	}
+	slices.Sort(syms)
+	return syms
+}
+
+// Added returns the tickers of class that the last Refresh added to the
+// universe (none on the first resolution of the class).
+func (u *Universe) Added(class string) []string {
+	return u.added[class]
+}
+
+// Targets returns one crawl Job per ticker of the last resolved universe.
+func (u *Universe) Targets() []crawl.Job {
+	timeframe := polygon.TimeframeLabel(u.cfg.Data.Timespan, u.cfg.Data.Multiplier)
 	var targets []crawl.Job
-	for class, tickers := range byClass {
-		targets = append(targets, crawl.BuildTargets(tickers, cfg.SaveBaseDir(), cfg.Provider, class)...)
+	for _, asset := range u.cfg.EnabledAssets() {
+		class := crawl.AssetClass(asset.Class)
+		tickers := u.last.Tickers[asset.Class]
+		for _, adj := range asset.Adjustments() {
+			for _, t := range crawl.BuildTargets(tickers, u.cfg.ClassDir(class, adj), u.cfg.Layout(), u.cfg.Provider, class, timeframe, adj) {
+				t.ActionsDir = u.cfg.ActionsDir(class)
+				targets = append(targets, t)
+			}
+		}
 	}
 	slog.Info("total jobs", "count", len(targets))
+	return targets
+}
 
-	if err := os.MkdirAll(cfg.Data.Dir, 0o755); err != nil {
-		return nil, fmt.Errorf("create data dir %q: %w", cfg.Data.Dir, err)
+// diffUniverse returns the tickers of next not in prev (added) and of prev not
+// in next (removed), sorted.
+func diffUniverse(at time.Time, class string, prev, next []string) UniverseChange {
+	c := UniverseChange{At: at, Class: class}
+	inPrev := make(map[string]bool, len(prev))
+	for _, s := range prev {
+		inPrev[s] = true
 	}
-	slog.Info("storage configured", "dir", cfg.SaveBaseDir(), "format", cfg.Data.Format)
-
-	return targets, nil
+	inNext := make(map[string]bool, len(next))
+	for _, s := range next {
+		inNext[s] = true
+		if !inPrev[s] {
+			c.Added = append(c.Added, s)
+		}
+	}
+	for _, s := range prev {
+		if !inNext[s] {
+			c.Removed = append(c.Removed, s)
+		}
+	}
+	slices.Sort(c.Added)
+	slices.Sort(c.Removed)
+	return c
 }
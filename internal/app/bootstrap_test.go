@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"us-data/internal/layout"
+	"us-data/internal/provider/polygon"
+)
+
+// testConfig returns a 5min config with the default layout, saving under a
+// temp dir.
+func testConfig(t *testing.T, assets ...AssetConfig) *Config {
+	t.Helper()
+	cfg := &Config{Provider: "massive", Assets: assets}
+	cfg.API.Keys = []string{"key"}
+	cfg.Data.Dir = t.TempDir()
+	cfg.Data.Timespan, cfg.Data.Multiplier = "minute", 5
+	l, err := layout.New(layout.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.layout = l
+	return cfg
+}
+
+// stocks is an enabled stocks asset with explicit tickers.
+func stocks(tickers ...string) AssetConfig {
+	return AssetConfig{Class: "stocks", Enabled: true, Tickers: tickers}
+}
+
+// notFoundAPI is a Crawler whose every reference lookup answers 404, so
+// validated tickers all resolve as invalid.
+func notFoundAPI(t *testing.T) *polygon.Crawler {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+	return &polygon.Crawler{BaseURL: srv.URL}
+}
+
+func TestUniverseRefresh(t *testing.T) {
+	failing := stocks("AAPL", "NVDA")
+	failing.Validate = true // every lookup fails: nothing resolves
+
+	tests := []struct {
+		name        string
+		next        AssetConfig
+		want        []string
+		wantAdded   []string
+		wantChanges int
+	}{
+		{"unchanged", stocks("AAPL", "MSFT"), []string{"AAPL", "MSFT"}, nil, 0},
+		{"changed", stocks("AAPL", "NVDA"), []string{"AAPL", "NVDA"}, []string{"NVDA"}, 1},
+		{"failed refresh keeps the previous universe", failing, []string{"AAPL", "MSFT"}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, stocks("AAPL", "MSFT"))
+			u, err := NewUniverse(cfg, notFoundAPI(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := u.Refresh(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if added := u.Added("stocks"); len(added) != 0 {
+				t.Errorf("first resolution added %v, want nothing to diff against", added)
+			}
+
+			cfg.Assets = []AssetConfig{tt.next}
+			if err := u.Refresh(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := u.last.Tickers["stocks"]; !slices.Equal(got, tt.want) {
+				t.Errorf("tickers = %v, want %v", got, tt.want)
+			}
+			if got := u.Added("stocks"); !slices.Equal(got, tt.wantAdded) {
+				t.Errorf("added = %v, want %v", got, tt.wantAdded)
+			}
+			if len(u.last.Changes) != tt.wantChanges {
+				t.Errorf("changes = %+v, want %d", u.last.Changes, tt.wantChanges)
+			}
+
+			// After a restart the persisted universe is the fallback.
+			cfg.Assets = []AssetConfig{failing}
+			restarted, err := NewUniverse(cfg, notFoundAPI(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := restarted.Refresh(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := restarted.last.Tickers["stocks"]; !slices.Equal(got, tt.want) {
+				t.Errorf("after restart tickers = %v, want the persisted %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithGraceMembers(t *testing.T) {
+	removed := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	now := removed.AddDate(0, 0, 10)
+	tests := []struct {
+		name  string
+		grace int
+		want  []string
+	}{
+		{"no grace period", 0, []string{"AAPL", "MSFT"}},
+		{"removed within the grace period", 30, []string{"AAPL", "MSFT", "XOM"}},
+		{"grace period over", 5, []string{"AAPL", "MSFT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.Data.MembershipGraceDays = tt.grace
+			u, err := NewUniverse(cfg, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u.recordMembership(removed.AddDate(0, -1, 0), "sp500", []string{"AAPL", "MSFT", "XOM"})
+			u.recordMembership(removed, "sp500", []string{"AAPL", "MSFT"})
+
+			got := u.withGraceMembers(now, AssetConfig{Class: "stocks", Groups: []string{"sp500"}}, []string{"MSFT", "AAPL"})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("tickers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniverseTargets(t *testing.T) {
+	tests := []struct {
+		adjustment string
+		wantDirs   []string // SaveDir of each target, relative to data.dir
+	}{
+		{"", []string{"Polygon/stocks", "Polygon/stocks"}},
+		{"raw", []string{"Polygon/raw/stocks", "Polygon/raw/stocks"}},
+		{"both", []string{"Polygon/stocks", "Polygon/stocks", "Polygon/raw/stocks", "Polygon/raw/stocks"}},
+	}
+	for _, tt := range tests {
+		t.Run("adjustment="+tt.adjustment, func(t *testing.T) {
+			asset := stocks()
+			asset.Adjustment = tt.adjustment
+			cfg := testConfig(t, asset)
+			u := &Universe{cfg: cfg, last: universeFile{Tickers: map[string][]string{"stocks": {"AAPL", "MSFT"}}}}
+
+			targets := u.Targets()
+
+			var dirs []string
+			for _, j := range targets {
+				rel, _ := filepath.Rel(cfg.Data.Dir, j.SaveDir)
+				dirs = append(dirs, filepath.ToSlash(rel))
+				if j.Timeframe != "5min" || j.ActionsDir != filepath.Join(cfg.Data.Dir, "Polygon", "stocks") {
+					t.Errorf("%s: timeframe %q, actions dir %q", j.Ticker, j.Timeframe, j.ActionsDir)
+				}
+			}
+			if !slices.Equal(dirs, tt.wantDirs) {
+				t.Errorf("save dirs = %v, want %v", dirs, tt.wantDirs)
+			}
+		})
+	}
+}
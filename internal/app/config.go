@@ -6,9 +6,13 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/spf13/viper"
+
+	"us-data/internal/crawl"
+	"us-data/internal/layout"
 )
 
 // logLevel is a package-level LevelVar so the log level can be changed at
@@ -22,6 +26,44 @@ type AssetConfig struct {
 	Groups   []string `mapstructure:"groups"`  // sp500 | nasdaq100 | dji | all
 	Tickers  []string `mapstructure:"tickers"` // explicit individual symbols
 	Validate bool     `mapstructure:"validate"`
+
+	CorporateActions bool `mapstructure:"corporateActions"` // stocks only: also crawl splits and dividends
+
+	Adjustment string `mapstructure:"adjustment"` // adjusted (default) | raw | both
+}
+
+// Adjustments returns the bar series to crawl for the asset: adjusted, raw,
+// or both (adjusted first).
+func (a AssetConfig) Adjustments() []crawl.Adjustment {
+	switch strings.ToLower(strings.TrimSpace(a.Adjustment)) {
+	case string(crawl.Raw):
+		return []crawl.Adjustment{crawl.Raw}
+	case "both":
+		return []crawl.Adjustment{crawl.Adjusted, crawl.Raw}
+	}
+	return []crawl.Adjustment{crawl.Adjusted}
+}
+
+// RateLimitConfig is the request budget of one API key.
+type RateLimitConfig struct {
+	RequestsPerMinute int `mapstructure:"requestsPerMinute"` // 0 = unthrottled (paid plans)
+	Burst             int `mapstructure:"burst"`             // back-to-back requests before throttling
+}
+
+// KeyRateLimitConfig overrides the default budget for keys starting with Prefix.
+// Use the 8-char prefix shown in logs so secrets stay out of config.yaml.
+type KeyRateLimitConfig struct {
+	Prefix          string `mapstructure:"prefix"`
+	RateLimitConfig `mapstructure:",squash"`
+}
+
+// RequestRetryConfig controls retries of a single API request (429, 5xx,
+// network and decode errors). A Retry-After header overrides the delay when longer.
+type RequestRetryConfig struct {
+	MaxAttempts  int     `mapstructure:"maxAttempts"`  // total tries per request
+	BaseDelaySec float64 `mapstructure:"baseDelaySec"` // wait before the 2nd try; doubles each try
+	MaxDelaySec  float64 `mapstructure:"maxDelaySec"`  // cap for the exponential delay
+	Jitter       float64 `mapstructure:"jitter"`       // ±fraction of random spread, 0..1
 }
 
 // Config is the application configuration loaded from config.yaml with env overrides.
@@ -29,7 +71,10 @@ type Config struct {
 	Provider string `mapstructure:"provider"`
 
 	API struct {
-		Keys []string `mapstructure:"keys"`
+		Keys          []string             `mapstructure:"keys"`
+		RateLimit     RateLimitConfig      `mapstructure:"rateLimit"`     // default budget for every key
+		KeyRateLimits []KeyRateLimitConfig `mapstructure:"keyRateLimits"` // per-key overrides
+		RequestRetry  RequestRetryConfig   `mapstructure:"requestRetry"`  // retries of a single API request
 	} `mapstructure:"api"`
 
 	Data struct {
@@ -38,8 +83,29 @@ type Config struct {
 		Timespan      string `mapstructure:"timespan"`      // minute | hour | day | week | month
 		Multiplier    int    `mapstructure:"multiplier"`    // e.g. 1, 5, 15
 		BackfillYears int    `mapstructure:"backfillYears"` // years of history on first run
+		SplitJobs     bool   `mapstructure:"splitJobs"`     // split long ranges into chunk jobs shared by all keys
+		Partition     string `mapstructure:"partition"`     // none | day | month | year: bar files per saved range or per period
+		Layout        string `mapstructure:"layout"`        // default | hive | a path template (see package layout)
+		ProgressStore string `mapstructure:"progressStore"` // json | sqlite
+
+		EmptyRange          string `mapstructure:"emptyRange"`          // auto | complete | fail
+		EmptyMaxTradingDays int    `mapstructure:"emptyMaxTradingDays"` // auto: longest empty range accepted as complete
+
+		MembershipGraceDays int `mapstructure:"membershipGraceDays"` // days removed index members are still crawled
+
+		RefetchOnSplit bool `mapstructure:"refetchOnSplit"` // rewrite adjusted history after a new split
+
+		MinFreeDiskMB int `mapstructure:"minFreeDiskMB"` // pause writes below this much free space; 0 = off
+
+		TrackRenames  bool   `mapstructure:"trackRenames"`  // carry progress over ticker symbol changes
+		SymbolMapFile string `mapstructure:"symbolMapFile"` // optional JSON list of {from, to, date} changes
 	} `mapstructure:"data"`
 
+	Retry struct {
+		MaxAttempts  int `mapstructure:"maxAttempts"`  // total tries per job in one cycle; 1 = no retry
+		BaseDelaySec int `mapstructure:"baseDelaySec"` // backoff before the 2nd attempt; doubles each pass
+	} `mapstructure:"retry"`
+
 	Schedule struct {
 		RunHour   int `mapstructure:"runHour"`
 		RunMinute int `mapstructure:"runMinute"`
@@ -52,6 +118,8 @@ type Config struct {
 	} `mapstructure:"log"`
 
 	Assets []AssetConfig `mapstructure:"assets"`
+
+	layout *layout.Layout // parsed Data.Layout
 }
 
 // LoadConfig reads config.yaml (or CONFIG_FILE env) then overlays secrets from env.
@@ -67,11 +135,29 @@ func LoadConfig() (*Config, error) {
 
 	// Defaults
 	v.SetDefault("provider", "massive")
+	v.SetDefault("api.rateLimit.requestsPerMinute", 5)
+	v.SetDefault("api.rateLimit.burst", 1)
+	v.SetDefault("api.requestRetry.maxAttempts", 3)
+	v.SetDefault("api.requestRetry.baseDelaySec", 15)
+	v.SetDefault("api.requestRetry.maxDelaySec", 120)
+	v.SetDefault("api.requestRetry.jitter", 0.2)
 	v.SetDefault("data.dir", "data")
 	v.SetDefault("data.format", "parquet")
 	v.SetDefault("data.timespan", "minute")
 	v.SetDefault("data.multiplier", 1)
 	v.SetDefault("data.backfillYears", 2)
+	v.SetDefault("data.splitJobs", true)
+	v.SetDefault("data.partition", "none")
+	v.SetDefault("data.layout", "default")
+	v.SetDefault("data.progressStore", "json")
+	v.SetDefault("data.emptyRange", "auto")
+	v.SetDefault("data.emptyMaxTradingDays", crawl.DefaultEmptyMaxTradingDays)
+	v.SetDefault("data.membershipGraceDays", 30)
+	v.SetDefault("data.refetchOnSplit", false)
+	v.SetDefault("data.trackRenames", false)
+	v.SetDefault("data.minFreeDiskMB", 0)
+	v.SetDefault("retry.maxAttempts", 3)
+	v.SetDefault("retry.baseDelaySec", 60)
 	v.SetDefault("schedule.runHour", 0)
 	v.SetDefault("schedule.runMinute", 30)
 	v.SetDefault("log.level", "info")
@@ -113,28 +199,97 @@ var validTimespans = map[string]bool{
 	"minute": true, "hour": true, "day": true, "week": true, "month": true,
 }
 
+// intraday reports whether bars are finer than a day.
+func (c *Config) intraday() bool {
+	ts := strings.ToLower(c.Data.Timespan)
+	return ts == "minute" || ts == "hour"
+}
+
 func validateConfig(cfg *Config) error {
 	if len(cfg.API.Keys) == 0 {
 		return fmt.Errorf("no API keys found: set POLYGON_API_KEYS env or api.keys in config.yaml")
 	}
+	if cfg.API.RateLimit.RequestsPerMinute < 0 || cfg.API.RateLimit.Burst < 0 {
+		return fmt.Errorf("api.rateLimit values must be >= 0, got requestsPerMinute=%d burst=%d",
+			cfg.API.RateLimit.RequestsPerMinute, cfg.API.RateLimit.Burst)
+	}
+	for i, k := range cfg.API.KeyRateLimits {
+		if strings.TrimSpace(k.Prefix) == "" {
+			return fmt.Errorf("api.keyRateLimits[%d].prefix must not be empty", i)
+		}
+		if k.RequestsPerMinute < 0 || k.Burst < 0 {
+			return fmt.Errorf("api.keyRateLimits[%d] values must be >= 0", i)
+		}
+	}
+	rr := cfg.API.RequestRetry
+	if rr.MaxAttempts < 1 {
+		return fmt.Errorf("api.requestRetry.maxAttempts must be >= 1, got %d", rr.MaxAttempts)
+	}
+	if rr.BaseDelaySec < 0 || rr.MaxDelaySec < 0 || rr.Jitter < 0 || rr.Jitter > 1 {
+		return fmt.Errorf("api.requestRetry: delays must be >= 0 and jitter within 0..1")
+	}
 	format := strings.ToLower(cfg.Data.Format)
 	if format != "parquet" && format != "csv" && format != "json" {
 		return fmt.Errorf("unsupported data.format %q (allowed: parquet, csv, json)", cfg.Data.Format)
 	}
+	if ps := strings.ToLower(cfg.Data.ProgressStore); ps != "json" && ps != "sqlite" {
+		return fmt.Errorf("unsupported data.progressStore %q (allowed: json, sqlite)", cfg.Data.ProgressStore)
+	}
+	l, err := layout.New(cfg.Data.Layout)
+	if err != nil {
+		return fmt.Errorf("data.layout: %w", err)
+	}
+	cfg.layout = l
+	switch crawl.Partition(strings.ToLower(cfg.Data.Partition)) {
+	case crawl.PartitionNone, crawl.PartitionDay, crawl.PartitionMonth, crawl.PartitionYear:
+	default:
+		return fmt.Errorf("unsupported data.partition %q (allowed: none, day, month, year)", cfg.Data.Partition)
+	}
+	switch crawl.EmptyMode(strings.ToLower(cfg.Data.EmptyRange)) {
+	case crawl.EmptyAuto, crawl.EmptyComplete, crawl.EmptyFail:
+	default:
+		return fmt.Errorf("unsupported data.emptyRange %q (allowed: auto, complete, fail)", cfg.Data.EmptyRange)
+	}
+	if cfg.Data.EmptyMaxTradingDays < 1 {
+		return fmt.Errorf("data.emptyMaxTradingDays must be >= 1, got %d (use emptyRange: fail to never accept an empty range)", cfg.Data.EmptyMaxTradingDays)
+	}
+	if cfg.Data.MinFreeDiskMB < 0 {
+		return fmt.Errorf("data.minFreeDiskMB must be >= 0, got %d", cfg.Data.MinFreeDiskMB)
+	}
+	if cfg.Data.MembershipGraceDays < 0 {
+		return fmt.Errorf("data.membershipGraceDays must be >= 0, got %d", cfg.Data.MembershipGraceDays)
+	}
 	if !validTimespans[strings.ToLower(cfg.Data.Timespan)] {
 		return fmt.Errorf("unsupported data.timespan %q (allowed: minute, hour, day, week, month)", cfg.Data.Timespan)
 	}
+	if cfg.intraday() && crawl.Partition(strings.ToLower(cfg.Data.Partition)) == crawl.PartitionYear {
+		return fmt.Errorf("data.partition year is not supported for %s bars: every daily increment would rewrite the whole year's file (use month or day)", cfg.Data.Timespan)
+	}
 	if cfg.Data.Multiplier <= 0 {
 		return fmt.Errorf("data.multiplier must be >= 1, got %d", cfg.Data.Multiplier)
 	}
 	if cfg.Data.BackfillYears <= 0 {
 		return fmt.Errorf("data.backfillYears must be >= 1, got %d", cfg.Data.BackfillYears)
 	}
+	if cfg.Retry.MaxAttempts < 1 {
+		return fmt.Errorf("retry.maxAttempts must be >= 1, got %d", cfg.Retry.MaxAttempts)
+	}
+	if cfg.Retry.BaseDelaySec < 0 {
+		return fmt.Errorf("retry.baseDelaySec must be >= 0, got %d", cfg.Retry.BaseDelaySec)
+	}
 	enabled := 0
 	for _, a := range cfg.Assets {
 		if a.Enabled {
 			enabled++
 		}
+		switch strings.ToLower(strings.TrimSpace(a.Adjustment)) {
+		case "", string(crawl.Adjusted), string(crawl.Raw), "both":
+		default:
+			return fmt.Errorf("assets[%s].adjustment %q unsupported (allowed: adjusted, raw, both)", a.Class, a.Adjustment)
+		}
+		if a.CorporateActions && crawl.AssetClass(strings.ToLower(a.Class)) != crawl.AssetStocks {
+			return fmt.Errorf("assets[%s].corporateActions is only supported for class stocks", a.Class)
+		}
 	}
 	if enabled == 0 {
 		return fmt.Errorf("no assets enabled in config.yaml; set at least one assets[].enabled: true")
@@ -160,9 +315,47 @@ func parseAPIKeysFromEnv() []string {
 	return keys
 }
 
-// SaveBaseDir returns the root directory for persisted market data.
+// sourceDir is the directory of the provider's data: {source} in layouts.
+const sourceDir = "Polygon"
+
+// SaveBaseDir returns the directory of the crawler's state files (progress,
+// reports, membership); with the default layout also the root of its bars.
 func (c *Config) SaveBaseDir() string {
-	return filepath.Join(c.Data.Dir, "Polygon")
+	return filepath.Join(c.Data.Dir, sourceDir)
+}
+
+// Layout returns the output layout of bar files.
+func (c *Config) Layout() *layout.Layout { return c.layout }
+
+// ClassDir returns the directory of the ticker directories of class under
+// the output layout, for raw or adjusted series.
+func (c *Config) ClassDir(class crawl.AssetClass, adj crawl.Adjustment) string {
+	return filepath.Join(c.Data.Dir, c.layout.ClassDir(sourceDir, string(class), adj == crawl.Raw))
+}
+
+// ActionsDir returns the directory of the ticker directories of class's
+// corporate actions under the output layout.
+func (c *Config) ActionsDir(class crawl.AssetClass) string {
+	return filepath.Join(c.Data.Dir, c.layout.ActionsDir(sourceDir, string(class)))
+}
+
+// Partition returns the bar partition: data.partition, made finer when the
+// layout names a finer period in its paths (hive: at least year).
+//
+// Every save into a partitioned file rewrites the whole file, so intraday
+// bars are never partitioned by year: a layout asking for year partitions
+// gets month files (under the year directories) instead, and a daily
+// increment rewrites a month of bars rather than a year.
+func (c *Config) Partition() crawl.Partition {
+	p := crawl.Partition(strings.ToLower(c.Data.Partition))
+	order := []crawl.Partition{crawl.PartitionNone, crawl.PartitionYear, crawl.PartitionMonth, crawl.PartitionDay}
+	if need := crawl.Partition(c.layout.Period()); slices.Index(order, need) > slices.Index(order, p) {
+		p = need
+	}
+	if p == crawl.PartitionYear && c.intraday() {
+		return crawl.PartitionMonth
+	}
+	return p
 }
 
 // ProgressPath returns the path to the per-ticker progress file.
@@ -170,6 +363,33 @@ func (c *Config) ProgressPath() string {
 	return filepath.Join(c.SaveBaseDir(), ".lastday.json")
 }
 
+// ProgressDBPath returns the path to the SQLite progress database.
+func (c *Config) ProgressDBPath() string {
+	return filepath.Join(c.SaveBaseDir(), ".progress.db")
+}
+
+// UniversePath returns the path to the last resolved ticker universe and its
+// change history.
+func (c *Config) UniversePath() string {
+	return filepath.Join(c.SaveBaseDir(), ".universe.json")
+}
+
+// MembershipDir returns the directory of index membership snapshots and
+// effective-date intervals, one subdirectory per group.
+func (c *Config) MembershipDir() string {
+	return filepath.Join(c.SaveBaseDir(), "membership")
+}
+
+// TickerDetailsPath returns the path to the ticker reference details cache.
+func (c *Config) TickerDetailsPath() string {
+	return filepath.Join(c.SaveBaseDir(), ".tickers.json")
+}
+
+// SymbolsPath returns the path to the ticker symbol change history.
+func (c *Config) SymbolsPath() string {
+	return filepath.Join(c.SaveBaseDir(), ".symbols.json")
+}
+
 // InitLogger installs the bootstrap logger (Info level, text format) before
 // config is loaded. Call ApplyLogger after loading config to apply the
 // configured level and format.
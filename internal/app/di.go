@@ -2,9 +2,16 @@ package app
 
 import (
 	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
 
+	"us-data/internal/crawl"
 	"us-data/internal/provider"
+	"us-data/internal/provider/polygon"
 	"us-data/internal/saver"
+	"us-data/internal/store"
 )
 
 // ProvideConfig loads application config. Used by Wire.
@@ -12,6 +19,59 @@ func ProvideConfig() (*Config, error) {
 	return LoadConfig()
 }
 
+// ProvideRateLimiter builds the per-key request budget from config, shared by
+// every Polygon request (aggregates and reference). Used by Wire.
+func ProvideRateLimiter(cfg *Config) *polygon.RateLimiter {
+	def := polygon.RateLimit{
+		RequestsPerMinute: cfg.API.RateLimit.RequestsPerMinute,
+		Burst:             cfg.API.RateLimit.Burst,
+	}
+	overrides := make(map[string]polygon.RateLimit, len(cfg.API.KeyRateLimits))
+	for _, k := range cfg.API.KeyRateLimits {
+		overrides[strings.TrimSpace(k.Prefix)] = polygon.RateLimit{
+			RequestsPerMinute: k.RequestsPerMinute,
+			Burst:             k.Burst,
+		}
+	}
+	return polygon.NewRateLimiter(def, overrides)
+}
+
+// ProvideTickerDetails builds the cache of ticker reference details (list
+// and delisting dates, active flag) shared by ticker validation and backfill
+// clamping. Used by Wire.
+func ProvideTickerDetails(cfg *Config) *polygon.TickerDetailsCache {
+	return polygon.NewTickerDetailsCache(cfg.TickerDetailsPath(), 0)
+}
+
+// ProvideProgressStore opens the configured progress backend. Used by Wire.
+//
+// For sqlite, an existing .lastday.json is imported once (legacy plain-ticker
+// keys included) and renamed to .lastday.json.migrated so it is never re-read.
+func ProvideProgressStore(cfg *Config) (crawl.ProgressStore, error) {
+	jsonStore := crawl.NewJSONProgressStore(cfg.ProgressPath())
+	if strings.ToLower(cfg.Data.ProgressStore) != "sqlite" {
+		return jsonStore, nil
+	}
+
+	db, err := store.NewSQLiteProgressStore(cfg.ProgressDBPath())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(cfg.ProgressPath()); err == nil {
+		n, err := crawl.MigrateProgress(jsonStore, db, cfg.Provider)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("migrate %s to sqlite: %w", cfg.ProgressPath(), err)
+		}
+		if err := os.Rename(cfg.ProgressPath(), cfg.ProgressPath()+".migrated"); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("retire migrated progress file: %w", err)
+		}
+		slog.Info("progress migrated to sqlite", "from", cfg.ProgressPath(), "to", cfg.ProgressDBPath(), "entries", n)
+	}
+	return db, nil
+}
+
 // ProvidePacketSaver constructs the bar persistence backend from config. Used by Wire.
 func ProvidePacketSaver(cfg *Config) (saver.PacketSaver, error) {
 	ps := saver.NewPacketSaver(cfg.Data.Format)
@@ -21,10 +81,25 @@ func ProvidePacketSaver(cfg *Config) (saver.PacketSaver, error) {
 	return ps, nil
 }
 
-// ProvidePolygonProvider constructs a fully configured PolygonProvider. Used by Wire.
-func ProvidePolygonProvider(cfg *Config, ps saver.PacketSaver) (*provider.PolygonProvider, error) {
+// ProvidePolygonProvider constructs a fully configured PolygonProvider whose
+// requests share rl and details. Used by Wire.
+func ProvidePolygonProvider(cfg *Config, ps saver.PacketSaver, rl *polygon.RateLimiter, details *polygon.TickerDetailsCache) (*provider.PolygonProvider, error) {
 	if len(cfg.API.Keys) == 0 {
 		return nil, fmt.Errorf("no API keys configured")
 	}
-	return provider.NewPolygonProvider(cfg.SaveBaseDir(), ps, cfg.Data.Timespan, cfg.Data.Multiplier)
+	p, err := provider.NewPolygonProvider(cfg.SaveBaseDir(), ps, cfg.Data.Timespan, cfg.Data.Multiplier)
+	if err != nil {
+		return nil, err
+	}
+	p.Crawler.Layout = cfg.Layout()
+	p.Crawler.RateLimiter = rl
+	p.Crawler.Details = details
+	rr := cfg.API.RequestRetry
+	p.Crawler.Retry = &polygon.RetryPolicy{
+		MaxAttempts: rr.MaxAttempts,
+		BaseDelay:   time.Duration(rr.BaseDelaySec * float64(time.Second)),
+		MaxDelay:    time.Duration(rr.MaxDelaySec * float64(time.Second)),
+		Jitter:      rr.Jitter,
+	}
+	return p, nil
 }
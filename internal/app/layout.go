@@ -0,0 +1,61 @@
+package app
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"us-data/internal/crawl"
+	"us-data/internal/layout"
+)
+
+// migrateTickerDirs renames the ticker directories written before symbols
+// were escaped in paths (crypto/X:BTCUSD → crypto/X%3ABTCUSD), together with
+// the ticker prefix of their files, so a series keeps its history in one
+// directory. Directories already in place are left alone.
+func migrateTickerDirs(targets []crawl.Job) {
+	done := make(map[string]bool)
+	for _, t := range targets {
+		if layout.Escape(t.Ticker) == t.Ticker || t.TickerDir == "" {
+			continue
+		}
+		old := filepath.Join(t.SaveDir, t.Ticker)
+		dst := filepath.Join(t.SaveDir, t.TickerDir)
+		if done[old] {
+			continue
+		}
+		done[old] = true
+		if _, err := os.Stat(old); err != nil {
+			continue
+		}
+		if _, err := os.Stat(dst); !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("unescaped ticker directory not migrated: target exists", "dir", old, "target", dst)
+			continue
+		}
+		if err := renameTickerFiles(old, t.Ticker); err != nil {
+			slog.Warn("unescaped ticker directory not migrated", "dir", old, "err", err)
+			continue
+		}
+		if err := os.Rename(old, dst); err != nil {
+			slog.Warn("unescaped ticker directory not migrated", "dir", old, "err", err)
+			continue
+		}
+		slog.Info("ticker directory migrated to escaped name", "from", old, "to", dst)
+	}
+}
+
+// renameTickerFiles renames the files below dir named {ticker}_… to the
+// escaped ticker, including those in partition subdirectories.
+func renameTickerFiles(dir, ticker string) error {
+	prefix, escaped := ticker+"_", layout.Escape(ticker)+"_"
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasPrefix(d.Name(), prefix) {
+			return err
+		}
+		to := escaped + strings.TrimPrefix(d.Name(), prefix)
+		return os.Rename(path, filepath.Join(filepath.Dir(path), to))
+	})
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"us-data/internal/crawl"
+	"us-data/internal/layout"
+)
+
+// tree returns the files below dir, relative and slash-separated.
+func tree(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestMigrateTickerDirs(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   []string
+	}{
+		{"default", []string{
+			"X%3ABTCUSD/2024/X%3ABTCUSD_5min_2024.csv",
+			"X%3ABTCUSD/X%3ABTCUSD_5min_2025-01-02_to_2025-01-31.csv",
+			"X%3ABTCUSD/notes.txt",
+		}},
+		{"hive", []string{
+			"ticker=X%3ABTCUSD/2024/X%3ABTCUSD_5min_2024.csv",
+			"ticker=X%3ABTCUSD/X%3ABTCUSD_5min_2025-01-02_to_2025-01-31.csv",
+			"ticker=X%3ABTCUSD/notes.txt",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			cfg := testConfig(t)
+			l, err := layout.New(tt.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.layout = l
+			classDir := cfg.ClassDir(crawl.AssetCrypto, crawl.Adjusted)
+			// Written by a release that kept the colon in paths.
+			for _, name := range []string{
+				"X:BTCUSD/X:BTCUSD_5min_2025-01-02_to_2025-01-31.csv",
+				"X:BTCUSD/2024/X:BTCUSD_5min_2024.csv",
+				"X:BTCUSD/notes.txt",
+			} {
+				p := filepath.Join(classDir, name)
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			targets := crawl.BuildTargets([]string{"X:BTCUSD"}, classDir, cfg.Layout(), "massive", crawl.AssetCrypto, "5min", crawl.Adjusted)
+
+			migrateTickerDirs(targets)
+
+			if got := tree(t, classDir); !slices.Equal(got, tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+
+			// Every later start finds the directory in place.
+			migrateTickerDirs(targets)
+
+			if got := tree(t, classDir); !slices.Equal(got, tt.want) {
+				t.Errorf("after rerun files = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"us-data/internal/crawl"
+	"us-data/internal/layout"
+	"us-data/internal/provider/polygon"
+	"us-data/internal/symbols"
+)
+
+// Renames carries crawl state over ticker symbol changes (FB → META). Before
+// each cycle, a ticker new to the universe without progress of its own is
+// looked up in the ticker events (stocks) and the local mapping file; when
+// one of its former symbols has progress, that progress moves to the new
+// symbol, so it resumes instead of backfilling from scratch.
+//
+// The old symbol is dropped from the targets from then on, the change is
+// recorded in .symbols.json and both ticker directories are linked under the
+// security's stable id (see package symbols).
+//
+// A new ticker whose events lookup fails is held back: it is kept out of the
+// targets, recorded as held in .symbols.json and looked up again every cycle,
+// so a transient failure cannot start a fresh backfill under the new symbol
+// and lose the migration for good.
+type Renames struct {
+	cfg      *Config
+	api      *polygon.Crawler // ticker events lookups
+	progress crawl.ProgressStore
+	log      *symbols.Log
+	mapping  []symbols.Change
+}
+
+// NewRenames loads the change history and the mapping file of cfg; ticker
+// events are looked up through api.
+func NewRenames(cfg *Config, progress crawl.ProgressStore, api *polygon.Crawler) (*Renames, error) {
+	l, err := symbols.OpenLog(cfg.SymbolsPath())
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := symbols.LoadMapping(cfg.Data.SymbolMapFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(mapping) > 0 {
+		slog.Info("symbol mapping loaded", "path", cfg.Data.SymbolMapFile, "changes", len(mapping))
+	}
+	return &Renames{cfg: cfg, api: api, progress: progress, log: l, mapping: mapping}, nil
+}
+
+// Apply migrates the progress of renamed tickers among targets and returns
+// targets without the symbols renamed away or held back. A lookup that fails
+// for good (e.g. 403) is logged only: the new symbol is then crawled as a
+// new ticker, as without tracking.
+func (r *Renames) Apply(ctx context.Context, u *Universe, targets []crawl.Job) []crawl.Job {
+	apiKey := ""
+	if len(r.cfg.API.Keys) > 0 {
+		apiKey = r.cfg.API.Keys[0]
+	}
+	recorded, changed := len(r.log.Changes), false
+	defer func() {
+		if len(r.log.Changes) > recorded || changed {
+			if err := r.log.Save(); err != nil {
+				slog.Warn("symbol change history write failed", "path", r.cfg.SymbolsPath(), "err", err)
+			}
+		}
+	}()
+
+	m, err := r.progress.Load()
+	if err != nil {
+		slog.Warn("symbol changes not checked: progress load failed", "err", err)
+		if apiKey != "" {
+			for _, ticker := range u.Added(string(crawl.AssetStocks)) {
+				changed = r.log.Hold(string(crawl.AssetStocks), ticker) || changed // checked next cycle
+			}
+		}
+		return r.withoutRenamed(targets)
+	}
+
+	now := time.Now().UTC()
+	for _, asset := range r.cfg.EnabledAssets() {
+		class := asset.Class
+		inClass := make(map[string]bool)
+		for _, t := range targets {
+			if string(t.Class) == class {
+				inClass[t.Ticker] = true
+			}
+		}
+
+		for _, c := range r.mapping {
+			if (c.Class != "" && c.Class != class) || !inClass[c.To] {
+				continue
+			}
+			c.Class = class
+			if !r.log.Has(c.Class, c.From, c.To) {
+				r.apply(c, now)
+			}
+		}
+
+		if crawl.AssetClass(class) != crawl.AssetStocks || apiKey == "" {
+			continue // ticker events cover stocks only
+		}
+		// Held tickers first: their lookup failed in an earlier cycle.
+		candidates := slices.Clone(r.log.Held[class])
+		for _, ticker := range u.Added(class) {
+			if !slices.Contains(candidates, ticker) {
+				candidates = append(candidates, ticker)
+			}
+		}
+		for _, ticker := range candidates {
+			if !inClass[ticker] || crawl.HasProgress(m, r.cfg.Provider, crawl.AssetClass(class), ticker) {
+				changed = r.log.Release(class, ticker) || changed // left the universe, or nothing to migrate to
+				continue
+			}
+			ev, err := r.api.GetTickerEvents(ctx, apiKey, ticker)
+			if err != nil {
+				var re crawl.Retryable
+				if errors.As(err, &re) && !re.Retryable() {
+					slog.Warn("ticker events lookup failed, crawled as a new ticker", "ticker", ticker, "err", err)
+					changed = r.log.Release(class, ticker) || changed
+					continue
+				}
+				slog.Warn("ticker events lookup failed, ticker held back until it succeeds", "ticker", ticker, "err", err)
+				changed = r.log.Hold(class, ticker) || changed
+				continue
+			}
+			changed = r.log.Release(class, ticker) || changed
+			for _, prev := range ev.Previous(ticker) {
+				if !crawl.HasProgress(m, r.cfg.Provider, crawl.AssetClass(class), prev.Ticker) {
+					continue
+				}
+				r.apply(symbols.Change{
+					Class: class, From: prev.Ticker, To: ticker, Date: ev.Since(ticker),
+					ID: ev.CompositeFIGI, Source: symbols.FromEvents,
+				}, now)
+				break
+			}
+		}
+	}
+	r.link()
+	return r.withoutRenamed(targets)
+}
+
+// apply moves the progress of c.From to c.To and records c. A failed move
+// is not recorded, so a mapping entry is tried again next cycle.
+func (r *Renames) apply(c symbols.Change, now time.Time) {
+	n, err := crawl.RenameProgress(r.progress, r.cfg.Provider, crawl.AssetClass(c.Class), c.From, c.To)
+	if err != nil {
+		slog.Warn("symbol change: progress not migrated", "from", c.From, "to", c.To, "err", err)
+		return
+	}
+	c.Applied, c.Migrated = now, n
+	r.log.Record(c)
+	slog.Info("symbol change applied", "class", c.Class, "from", c.From, "to", c.To,
+		"date", c.Date, "id", c.StableID(), "source", c.Source, "progress_entries", n)
+}
+
+// link (re)creates the stable-id links of every recorded change. A new
+// symbol's directory appears only after its first save, so this runs every
+// cycle; existing links are kept.
+func (r *Renames) link() {
+	l := r.cfg.Layout()
+	for _, c := range r.log.Changes {
+		class := crawl.AssetClass(c.Class)
+		dirs := []string{r.cfg.ClassDir(class, crawl.Adjusted), r.cfg.ClassDir(class, crawl.Raw)}
+		if d := r.cfg.ActionsDir(class); !slices.Contains(dirs, d) {
+			dirs = append(dirs, d) // actions in a tree of their own
+		}
+		for _, dir := range dirs {
+			err := symbols.Link(dir, layout.Escape(c.StableID()), l.TickerDir(c.From), l.TickerDir(c.To))
+			if err != nil {
+				slog.Warn("symbol change: directories not linked", "from", c.From, "to", c.To, "err", err)
+			}
+		}
+	}
+}
+
+// withoutRenamed drops the targets whose symbol was renamed away, as the new
+// symbol carries on the series, and those held back until their lookup
+// succeeds.
+func (r *Renames) withoutRenamed(targets []crawl.Job) []crawl.Job {
+	out := targets[:0:0]
+	var dropped, held []string
+	for _, t := range targets {
+		switch {
+		case r.log.RenamedAway(string(t.Class), t.Ticker):
+			dropped = append(dropped, t.Ticker)
+		case r.log.IsHeld(string(t.Class), t.Ticker):
+			held = append(held, t.Ticker)
+		default:
+			out = append(out, t)
+		}
+	}
+	if len(dropped) > 0 {
+		slog.Info("renamed tickers skipped", "tickers", dropped)
+	}
+	if len(held) > 0 {
+		slog.Info("tickers held back until their symbol change lookup succeeds", "tickers", held)
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"us-data/internal/crawl"
+	"us-data/internal/provider/polygon"
+	"us-data/internal/symbols"
+)
+
+// eventsAPI is a Crawler whose ticker events name FB as META's former symbol.
+// NEWCO's lookup answers 503 while *unavailable is set.
+func eventsAPI(t *testing.T, unavailable *bool) *polygon.Crawler {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vX/reference/tickers/META/events":
+			w.Write([]byte(`{"results": {"composite_figi": "BBG000MM2P62", "events": [
+				{"type": "ticker_change", "date": "2012-05-18", "ticker_change": {"ticker": "FB"}},
+				{"type": "ticker_change", "date": "2022-06-09", "ticker_change": {"ticker": "META"}}]}}`))
+		case "/vX/reference/tickers/NEWCO/events":
+			if *unavailable {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"results": {"events": [
+				{"type": "ticker_change", "date": "2025-06-02", "ticker_change": {"ticker": "NEWCO"}}]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return &polygon.Crawler{BaseURL: srv.URL}
+}
+
+// tickers returns the tickers of targets, in order.
+func tickers(targets []crawl.Job) []string {
+	var out []string
+	for _, t := range targets {
+		out = append(out, t.Ticker)
+	}
+	return out
+}
+
+func TestRenamesApply(t *testing.T) {
+	cfg := testConfig(t, stocks("FB", "META", "NEWCO"))
+	classDir := cfg.ClassDir(crawl.AssetStocks, crawl.Adjusted)
+	for _, dir := range []string{"FB", "META"} {
+		if err := os.MkdirAll(filepath.Join(classDir, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	progress := crawl.NewJSONProgressStore(cfg.ProgressPath())
+	if err := progress.Put(map[string]string{
+		"massive:stocks:FB@5min":          "2022-06-08",
+		"massive:stocks:FB@5min#earliest": "2020-06-01",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	unavailable := true
+	r, err := NewRenames(cfg, progress, eventsAPI(t, &unavailable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &Universe{cfg: cfg, added: map[string][]string{"stocks": {"META", "NEWCO"}}}
+	targets := crawl.BuildTargets([]string{"FB", "META", "NEWCO"}, classDir, cfg.Layout(), "massive", crawl.AssetStocks, "5min", crawl.Adjusted)
+
+	got := r.Apply(context.Background(), u, targets)
+
+	if names := tickers(got); len(names) != 1 || names[0] != "META" {
+		t.Errorf("targets = %v, want META only: FB renamed away, NEWCO held", names)
+	}
+	m, err := progress.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["massive:stocks:META@5min"] != "2022-06-08" || m["massive:stocks:META@5min#earliest"] != "2020-06-01" || crawl.HasProgress(m, "massive", crawl.AssetStocks, "FB") {
+		t.Errorf("progress = %v, want FB's moved to META", m)
+	}
+	for _, dir := range []string{"FB", "META"} {
+		if fi, err := os.Lstat(filepath.Join(classDir, ".by-id", "BBG000MM2P62", dir)); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			t.Errorf(".by-id link to %s: %v", dir, err)
+		}
+	}
+	log, err := symbols.OpenLog(cfg.SymbolsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !log.IsHeld("stocks", "NEWCO") || !log.RenamedAway("stocks", "FB") {
+		t.Errorf(".symbols.json = %+v, want FB → META recorded and NEWCO held", log)
+	}
+
+	// The next cycle looks the held ticker up again, though no longer new.
+	unavailable = false
+	u.added = nil
+	got = r.Apply(context.Background(), u, targets)
+
+	if names := tickers(got); len(names) != 2 || names[0] != "META" || names[1] != "NEWCO" {
+		t.Errorf("targets = %v, want META and the released NEWCO", names)
+	}
+	if r.log.IsHeld("stocks", "NEWCO") {
+		t.Error("NEWCO still held after a successful lookup")
+	}
+}
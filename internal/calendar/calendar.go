@@ -0,0 +1,127 @@
+// Package calendar knows when markets trade: exchange sessions, holidays and
+// early closes for US equities, and the round-the-clock calendars of crypto
+// (24/7) and forex (24/5).
+//
+// Days are calendar dates (any time.Time; only year, month and day are used)
+// interpreted in the calendar's own Location, e.g. America/New_York for NYSE.
+package calendar
+
+import (
+	"time"
+	_ "time/tzdata" // sessions must resolve even on hosts without a zoneinfo database
+)
+
+// Session is one trading day.
+//
+// Open/Close bound the regular session. ExtOpen/ExtClose bound the extended
+// session (pre- and post-market), which is what Polygon aggregates cover; for
+// markets without extended hours they equal Open/Close.
+type Session struct {
+	Day        time.Time // trading date, 00:00 UTC
+	Open       time.Time
+	Close      time.Time
+	ExtOpen    time.Time
+	ExtClose   time.Time
+	EarlyClose bool
+}
+
+// Calendar answers whether and when a market trades on a given day.
+type Calendar interface {
+	Name() string
+	// Location is the time zone that defines the market's trading date.
+	Location() *time.Location
+	// Session returns the session on day, or ok=false when the market is
+	// closed all day (weekend, holiday).
+	Session(day time.Time) (s Session, ok bool)
+}
+
+// ForClass returns the calendar of an asset class (crawl.AssetClass values).
+// Unknown classes get Always, which never skips a day.
+//
+//	stocks, indices → NYSE    crypto → Always (24/7)    forex → Forex (24/5)
+func ForClass(class string) Calendar {
+	switch class {
+	case "stocks", "indices":
+		return NYSE
+	case "forex":
+		return Forex
+	default:
+		return Always
+	}
+}
+
+// IsTradingDay reports whether cal has a session on day.
+func IsTradingDay(cal Calendar, day time.Time) bool {
+	_, ok := cal.Session(day)
+	return ok
+}
+
+// TradingDays returns the trading dates (00:00 UTC) in [from, to], inclusive.
+func TradingDays(cal Calendar, from, to time.Time) []time.Time {
+	var out []time.Time
+	for d := Date(from); !d.After(Date(to)); d = d.AddDate(0, 0, 1) {
+		if IsTradingDay(cal, d) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Trim narrows [from, to] to the extended sessions it overlaps: from moves
+// forward to the ExtOpen of the first one and to moves back to just before
+// the ExtClose of the last one. Bounds inside a session are kept. ok is false
+// when the range overlaps no session at all.
+//
+// Sessions are matched by instant, not by UTC date: the NYSE post-market of
+// a Friday ends at 01:00 UTC on Saturday in winter, so a Saturday-only range
+// keeps that first hour.
+func Trim(cal Calendar, from, to time.Time) (time.Time, time.Time, bool) {
+	var first, last Session
+	found := false
+	for d := Date(from).AddDate(0, 0, -1); !d.After(Date(to).AddDate(0, 0, 1)); d = d.AddDate(0, 0, 1) {
+		s, ok := cal.Session(d)
+		if !ok || s.ExtOpen.After(to) || !s.ExtClose.After(from) {
+			continue
+		}
+		if !found {
+			first, found = s, true
+		}
+		last = s
+	}
+	if !found {
+		return time.Time{}, time.Time{}, false
+	}
+	if first.ExtOpen.After(from) {
+		from = first.ExtOpen.UTC()
+	}
+	if end := last.ExtClose.Add(-time.Millisecond); end.Before(to) {
+		to = end.UTC()
+	}
+	return from, to, true
+}
+
+// ExpectedBars returns how many bars of the given interval the extended
+// session on day can hold: 0 on closed days, at least 1 on trading days
+// (daily and longer intervals yield exactly 1).
+func ExpectedBars(cal Calendar, day time.Time, interval time.Duration) int {
+	s, ok := cal.Session(day)
+	if !ok {
+		return 0
+	}
+	if interval <= 0 || interval >= 24*time.Hour {
+		return 1
+	}
+	n := int((s.ExtClose.Sub(s.ExtOpen) + interval - 1) / interval)
+	return max(n, 1)
+}
+
+// DayOf returns the trading date of instant t: its calendar date in cal's
+// Location, as 00:00 UTC.
+func DayOf(cal Calendar, t time.Time) time.Time {
+	return Date(t.In(cal.Location()))
+}
+
+// Date truncates t to its calendar date at 00:00 UTC.
+func Date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
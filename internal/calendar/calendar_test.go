@@ -0,0 +1,111 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNYSEHolidays(t *testing.T) {
+	closed := []string{
+		"2024-01-01", "2024-01-15", "2024-02-19", "2024-03-29", "2024-05-27",
+		"2024-06-19", "2024-07-04", "2024-09-02", "2024-11-28", "2024-12-25",
+		"2025-01-09", // day of mourning
+		"2026-07-03", // July 4 on Saturday, observed Friday
+		"2023-01-02", // Jan 1 on Sunday, observed Monday
+		"2024-03-30", // Saturday
+	}
+	for _, s := range closed {
+		if IsTradingDay(NYSE, day(s)) {
+			t.Errorf("%s should be closed", s)
+		}
+	}
+	open := []string{"2024-03-28", "2021-12-31", "2021-06-18", "2024-07-05"}
+	for _, s := range open {
+		if !IsTradingDay(NYSE, day(s)) {
+			t.Errorf("%s should be open", s)
+		}
+	}
+}
+
+func TestNYSEEarlyClose(t *testing.T) {
+	for _, s := range []string{"2024-07-03", "2024-11-29", "2024-12-24"} {
+		sess, ok := NYSE.Session(day(s))
+		if !ok || !sess.EarlyClose {
+			t.Fatalf("%s: want early close, got ok=%v %+v", s, ok, sess)
+		}
+		if got := sess.Close.In(newYork).Hour(); got != 13 {
+			t.Errorf("%s: close hour = %d, want 13", s, got)
+		}
+	}
+	// 5-minute bars over the 04:00–20:00 ET extended session.
+	if got := ExpectedBars(NYSE, day("2024-07-02"), 5*time.Minute); got != 192 {
+		t.Errorf("ExpectedBars regular day = %d, want 192", got)
+	}
+	if got := ExpectedBars(NYSE, day("2024-07-03"), 5*time.Minute); got != 156 {
+		t.Errorf("ExpectedBars early close = %d, want 156", got)
+	}
+}
+
+func TestForexWeek(t *testing.T) {
+	if IsTradingDay(Forex, day("2024-06-15")) {
+		t.Error("Saturday should be closed")
+	}
+	sun, ok := Forex.Session(day("2024-06-16"))
+	if !ok || sun.Open.UTC().Hour() != 21 {
+		t.Errorf("Sunday session = %+v, want open 21:00 UTC (17:00 EDT)", sun)
+	}
+	if got := ExpectedBars(Forex, day("2024-06-14"), time.Hour); got != 21 {
+		t.Errorf("Friday hourly bars = %d, want 21", got)
+	}
+	if got := ExpectedBars(Always, day("2024-06-15"), time.Minute); got != 1440 {
+		t.Errorf("crypto Saturday minute bars = %d, want 1440", got)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	// Sat..Mon → Mon from its pre-market (04:00 EDT = 08:00 UTC), keeping the
+	// end-of-day bound.
+	from, to, ok := Trim(NYSE, day("2024-06-15"), day("2024-06-17").Add(24*time.Hour-time.Millisecond))
+	if !ok || !from.Equal(day("2024-06-17").Add(8*time.Hour)) || !to.Equal(day("2024-06-17").Add(24*time.Hour-time.Millisecond)) {
+		t.Errorf("Trim weekend = %v..%v ok=%v", from, to, ok)
+	}
+	// In winter Friday's post-market (until 20:00 EST) ends at 01:00 UTC on
+	// Saturday: a Saturday-only range keeps that hour.
+	from, to, ok = Trim(NYSE, day("2024-01-06"), day("2024-01-07").Add(24*time.Hour-time.Millisecond))
+	if !ok || !from.Equal(day("2024-01-06")) || !to.Equal(day("2024-01-06").Add(time.Hour-time.Millisecond)) {
+		t.Errorf("Trim winter Saturday = %v..%v ok=%v, want 2024-01-06 00:00..00:59:59.999", from, to, ok)
+	}
+	// In summer it ends at 00:00 UTC: nothing is left.
+	if from, to, ok := Trim(NYSE, day("2024-06-15"), day("2024-06-16").Add(24*time.Hour-time.Millisecond)); ok {
+		t.Errorf("Trim summer weekend = %v..%v, want no session", from, to)
+	}
+	// Likewise a holiday: Thanksgiving 2023 keeps the post-market hour of the
+	// Wednesday before it.
+	if _, to, ok := Trim(NYSE, day("2023-11-23"), day("2023-11-23").Add(24*time.Hour-time.Millisecond)); !ok || !to.Equal(day("2023-11-23").Add(time.Hour-time.Millisecond)) {
+		t.Errorf("Trim Thanksgiving 2023 = %v ok=%v, want the eve's post-market hour", to, ok)
+	}
+	// Crypto never trims.
+	from, to, ok = Trim(Always, day("2024-06-15"), day("2024-06-16").Add(24*time.Hour-time.Millisecond))
+	if !ok || !from.Equal(day("2024-06-15")) || !to.Equal(day("2024-06-16").Add(24*time.Hour-time.Millisecond)) {
+		t.Errorf("Trim crypto weekend = %v..%v ok=%v", from, to, ok)
+	}
+	// Thanksgiving + weekend has one session (the early-close Friday) …
+	if _, _, ok := Trim(NYSE, day("2024-11-28"), day("2024-12-01")); !ok {
+		t.Error("Trim should keep the day after Thanksgiving")
+	}
+	// … but a holiday-only range has none.
+	if _, _, ok := Trim(NYSE, day("2024-12-25"), day("2024-12-25")); ok {
+		t.Error("Trim of Christmas should report no session")
+	}
+	if n := len(TradingDays(NYSE, day("2024-12-23"), day("2024-12-29"))); n != 4 {
+		t.Errorf("TradingDays Christmas week = %d, want 4", n)
+	}
+}
@@ -0,0 +1,42 @@
+package calendar
+
+import "time"
+
+// Always is the 24/7 calendar of crypto: every UTC day is a full session.
+var Always Calendar = always{}
+
+// Forex is the 24/5 calendar of spot FX: the market opens Sunday 17:00 New
+// York time and closes Friday 17:00 New York time. Days are UTC dates, so
+// Sunday and Friday are partial sessions and Saturday is closed.
+var Forex Calendar = forex{}
+
+type always struct{}
+
+func (always) Name() string             { return "24/7" }
+func (always) Location() *time.Location { return time.UTC }
+
+func (always) Session(day time.Time) (Session, bool) {
+	d := Date(day)
+	end := d.Add(24 * time.Hour)
+	return Session{Day: d, Open: d, Close: end, ExtOpen: d, ExtClose: end}, true
+}
+
+type forex struct{}
+
+func (forex) Name() string             { return "24/5" }
+func (forex) Location() *time.Location { return time.UTC }
+
+func (forex) Session(day time.Time) (Session, bool) {
+	d := Date(day)
+	open, close := d, d.Add(24*time.Hour)
+	fivePM := time.Date(d.Year(), d.Month(), d.Day(), 17, 0, 0, 0, newYork)
+	switch d.Weekday() {
+	case time.Saturday:
+		return Session{}, false
+	case time.Sunday:
+		open = fivePM
+	case time.Friday:
+		close = fivePM
+	}
+	return Session{Day: d, Open: open, Close: close, ExtOpen: open, ExtClose: close}, true
+}
@@ -0,0 +1,161 @@
+package calendar
+
+import "time"
+
+var newYork = mustLoad("America/New_York")
+
+// NYSE and Nasdaq share the same holidays and hours:
+// regular 09:30–16:00 ET (13:00 on early-close days) and extended
+// 04:00–20:00 ET (17:00 on early-close days).
+var (
+	NYSE   Calendar = usEquities{name: "NYSE"}
+	Nasdaq Calendar = usEquities{name: "Nasdaq"}
+)
+
+type usEquities struct{ name string }
+
+func (c usEquities) Name() string             { return c.name }
+func (c usEquities) Location() *time.Location { return newYork }
+
+func (c usEquities) Session(day time.Time) (Session, bool) {
+	d := Date(day)
+	if wd := d.Weekday(); wd == time.Saturday || wd == time.Sunday || usHoliday(d) {
+		return Session{}, false
+	}
+	at := func(h, m int) time.Time {
+		return time.Date(d.Year(), d.Month(), d.Day(), h, m, 0, 0, newYork)
+	}
+	s := Session{
+		Day:     d,
+		Open:    at(9, 30),
+		Close:   at(16, 0),
+		ExtOpen: at(4, 0), ExtClose: at(20, 0),
+	}
+	if usEarlyClose(d) {
+		s.Close, s.ExtClose, s.EarlyClose = at(13, 0), at(17, 0), true
+	}
+	return s, true
+}
+
+// usSpecialClosures are unscheduled full-day closures (national days of
+// mourning, weather) not covered by the holiday rules.
+var usSpecialClosures = map[string]bool{
+	"2001-09-11": true, // September 11 attacks, closed through 09-14
+	"2001-09-12": true,
+	"2001-09-13": true,
+	"2001-09-14": true,
+	"2004-06-11": true, // President Reagan
+	"2007-01-02": true, // President Ford
+	"2012-10-29": true, // Hurricane Sandy
+	"2012-10-30": true,
+	"2018-12-05": true, // President G.H.W. Bush
+	"2025-01-09": true, // President Carter
+}
+
+// usHoliday reports whether d is an NYSE full-day holiday.
+func usHoliday(d time.Time) bool {
+	if usSpecialClosures[d.Format("2006-01-02")] {
+		return true
+	}
+	y := d.Year()
+	holidays := []time.Time{
+		newYearsObserved(y),
+		nthWeekday(y, time.January, time.Monday, 3),    // Martin Luther King Jr. Day
+		nthWeekday(y, time.February, time.Monday, 3),   // Washington's Birthday
+		easter(y).AddDate(0, 0, -2),                    // Good Friday
+		lastWeekday(y, time.May, time.Monday),          // Memorial Day
+		observed(ymd(y, time.July, 4)),                 // Independence Day
+		nthWeekday(y, time.September, time.Monday, 1),  // Labor Day
+		nthWeekday(y, time.November, time.Thursday, 4), // Thanksgiving
+		observed(ymd(y, time.December, 25)),            // Christmas
+	}
+	if y >= 2022 {
+		holidays = append(holidays, observed(ymd(y, time.June, 19))) // Juneteenth
+	}
+	for _, h := range holidays {
+		if d.Equal(h) {
+			return true
+		}
+	}
+	return false
+}
+
+// usEarlyClose reports whether d closes at 13:00 ET: the day before
+// Independence Day, the day after Thanksgiving and Christmas Eve, when those
+// are trading days.
+func usEarlyClose(d time.Time) bool {
+	y := d.Year()
+	switch {
+	case d.Equal(ymd(y, time.July, 3)),
+		d.Equal(nthWeekday(y, time.November, time.Thursday, 4).AddDate(0, 0, 1)),
+		d.Equal(ymd(y, time.December, 24)):
+		return true
+	}
+	return false
+}
+
+// newYearsObserved moves Jan 1 on a Sunday to Monday. NYSE does not close on
+// Friday Dec 31 when Jan 1 falls on a Saturday, so that year has no holiday.
+func newYearsObserved(y int) time.Time {
+	d := ymd(y, time.January, 1)
+	switch d.Weekday() {
+	case time.Sunday:
+		return d.AddDate(0, 0, 1)
+	case time.Saturday:
+		return time.Time{}
+	}
+	return d
+}
+
+// observed moves a Saturday holiday to Friday and a Sunday one to Monday.
+func observed(d time.Time) time.Time {
+	switch d.Weekday() {
+	case time.Saturday:
+		return d.AddDate(0, 0, -1)
+	case time.Sunday:
+		return d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// nthWeekday returns the n-th (1-based) wd of month.
+func nthWeekday(y int, month time.Month, wd time.Weekday, n int) time.Time {
+	d := ymd(y, month, 1)
+	offset := (int(wd) - int(d.Weekday()) + 7) % 7
+	return d.AddDate(0, 0, offset+7*(n-1))
+}
+
+// lastWeekday returns the last wd of month.
+func lastWeekday(y int, month time.Month, wd time.Weekday) time.Time {
+	d := ymd(y, month+1, 1).AddDate(0, 0, -1)
+	offset := (int(d.Weekday()) - int(wd) + 7) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+// easter returns Western Easter Sunday (anonymous Gregorian algorithm).
+func easter(y int) time.Time {
+	a := y % 19
+	b, c := y/100, y%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return ymd(y, time.Month(month), day)
+}
+
+func ymd(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func mustLoad(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic("calendar: load " + name + ": " + err.Error())
+	}
+	return loc
+}
@@ -0,0 +1,149 @@
+package crawl
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"us-data/internal/model"
+)
+
+// actionsTimeframe is the progress key suffix of the corporate actions stage
+// (source:class:TICKER@actions → last day whose actions are saved).
+const actionsTimeframe = "actions"
+
+// actionsReport is the corporate actions section of .lastrun.json.
+type actionsReport struct {
+	Tickers   int           `json:"tickers"`
+	Splits    int           `json:"splits"`
+	Dividends int           `json:"dividends"`
+	Failed    []reportEntry `json:"failed,omitempty"`
+}
+
+// actionResult is the outcome of one ticker in the actions stage.
+type actionResult struct {
+	target  Job
+	from    time.Time
+	to      time.Time
+	actions []model.CorporateAction
+	err     error
+}
+
+// runActions is the optional corporate actions stage of a cycle: for every
+// target of ActionClasses it fetches the splits and dividends effective since
+// the last run (the whole history on the first) up to yesterday, saves them
+// under the target's ActionsDir and advances their own progress key. One worker per API
+// key, as for bars; a failed ticker is retried from the same day next cycle.
+func (r *Runner) runActions(ctx context.Context, af ActionFetcher, now time.Time) *actionsReport {
+	classes := make(map[AssetClass]bool, len(r.ActionClasses))
+	for _, c := range r.ActionClasses {
+		classes[c] = true
+	}
+	m, err := r.Progress.Load()
+	if err != nil {
+		slog.Error("actions: progress load failed, stage skipped", "err", err)
+		return nil
+	}
+
+	to := date(now).AddDate(0, 0, -1)
+	var pending []actionResult
+	seen := make(map[string]bool)
+	for _, t := range r.Targets {
+		// Adjusted and raw targets of a ticker share its actions: saved once,
+		// under the first series' ActionsDir.
+		id := progressKey(t.Source, t.Class, t.Ticker, actionsTimeframe)
+		if !classes[t.Class] || seen[id] {
+			continue
+		}
+		seen[id] = true
+		var from time.Time // zero: whole history
+		if last, ok := m[id]; ok {
+			d, err := time.Parse("2006-01-02", last)
+			if err != nil {
+				slog.Warn("actions: bad progress date, refetching history", "ticker", t.Ticker, "value", last)
+			} else {
+				from = d.AddDate(0, 0, 1)
+			}
+		}
+		if from.After(to) {
+			continue // already up to date
+		}
+		pending = append(pending, actionResult{target: t, from: from, to: to})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	slog.Info("actions stage start", "tickers", len(pending), "workers", len(r.APIKeys))
+
+	jobs := make(chan actionResult)
+	results := make(chan actionResult)
+	var wg sync.WaitGroup
+	for _, key := range r.APIKeys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range jobs {
+				a.actions, a.err = af.FetchActions(ctx, a.target.Ticker, key, a.from, a.to)
+				if a.err == nil {
+					a.err = af.SaveActions(a.target.actionsDir(), a.target.Ticker, a.actions)
+				}
+				results <- a
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, a := range pending {
+			select {
+			case jobs <- a:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	rep := &actionsReport{}
+	for a := range results {
+		t := a.target
+		if a.err != nil {
+			slog.Warn("actions: ticker failed", "ticker", t.Ticker, "err", a.err)
+			rep.Failed = append(rep.Failed, reportEntry{
+				Ticker: t.Ticker, Class: string(t.Class),
+				DateRange: formatRange(a.from, a.to), Reason: a.err.Error(),
+				ErrorKind: errorKind(a.err), Attempts: 1,
+			})
+			continue
+		}
+		rep.Tickers++
+		for _, act := range a.actions {
+			switch act.Type {
+			case model.ActionSplit:
+				rep.Splits++
+				slog.Info("split", "ticker", t.Ticker, "date", act.Date, "from", act.SplitFrom, "to", act.SplitTo)
+			case model.ActionDividend:
+				rep.Dividends++
+			}
+		}
+		r.ProgressUpdates <- ProgressUpdate{
+			Source: t.Source, Class: t.Class, Ticker: t.Ticker,
+			Timeframe: actionsTimeframe, Date: a.to.Format("2006-01-02"),
+		}
+	}
+	slog.Info("actions stage done", "tickers", rep.Tickers, "splits", rep.Splits,
+		"dividends", rep.Dividends, "failed", len(rep.Failed))
+	return rep
+}
+
+// formatRange renders [from, to] as in the run report; a zero from is the
+// start of history.
+func formatRange(from, to time.Time) string {
+	if from.IsZero() {
+		return "..." + to.Format("2006-01-02")
+	}
+	return from.Format("2006-01-02") + ".." + to.Format("2006-01-02")
+}
@@ -0,0 +1,100 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"us-data/internal/layout"
+	"us-data/internal/model"
+)
+
+// fakeActions is an ActionFetcher serving canned actions; tickers in fail
+// return their error from FetchActions.
+type fakeActions struct {
+	mu      sync.Mutex
+	actions map[string][]model.CorporateAction
+	fail    map[string]error
+	from    map[string]time.Time // from of each FetchActions call
+	saved   map[string]int       // actions passed to SaveActions
+	dirs    map[string]string    // dir of each SaveActions call
+}
+
+func (f *fakeActions) FetchActions(_ context.Context, ticker, _ string, from, _ time.Time) ([]model.CorporateAction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.from[ticker] = from
+	if err := f.fail[ticker]; err != nil {
+		return nil, err
+	}
+	return f.actions[ticker], nil
+}
+
+func (f *fakeActions) SaveActions(dir, ticker string, actions []model.CorporateAction) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.saved[ticker] += len(actions)
+	f.dirs[ticker] = dir
+	return nil
+}
+
+func TestRunActions(t *testing.T) {
+	store := NewJSONProgressStore(filepath.Join(t.TempDir(), ".lastday.json"))
+	if err := store.Put(map[string]string{"massive:stocks:MSFT@actions": "2025-05-30"}); err != nil {
+		t.Fatal(err)
+	}
+	l, _ := layout.New(layout.Default)
+	targets := BuildTargets([]string{"AAPL", "MSFT", "FAIL"}, "data", l, "massive", AssetStocks, "5min", Adjusted)
+	targets = append(targets, BuildTargets([]string{"AAPL"}, "data/raw", l, "massive", AssetStocks, "5min", Raw)...)
+	targets = append(targets, BuildTargets([]string{"X:BTCUSD"}, "data", l, "massive", AssetCrypto, "5min", Adjusted)...)
+	targets[0].ActionsDir = "actions" // AAPL's actions have a tree of their own
+
+	af := &fakeActions{
+		actions: map[string][]model.CorporateAction{
+			"AAPL": {{ID: "s1", Type: model.ActionSplit, Date: "2020-08-31", SplitFrom: 1, SplitTo: 4}, {ID: "d1", Type: model.ActionDividend, Date: "2024-05-10"}},
+		},
+		fail:  map[string]error{"FAIL": errors.New("boom")},
+		from:  make(map[string]time.Time),
+		saved: make(map[string]int),
+		dirs:  make(map[string]string),
+	}
+	updates := make(chan ProgressUpdate, 16)
+	r := &Runner{
+		APIKeys: []string{"k1", "k2"}, Targets: targets, Progress: store,
+		ProgressUpdates: updates, ActionClasses: []AssetClass{AssetStocks},
+	}
+	rep := r.runActions(context.Background(), af, time.Date(2025, 6, 2, 6, 0, 0, 0, time.UTC))
+	close(updates)
+
+	if rep == nil || rep.Tickers != 2 || rep.Splits != 1 || rep.Dividends != 1 {
+		t.Fatalf("report = %+v, want 2 tickers, 1 split, 1 dividend", rep)
+	}
+	if len(rep.Failed) != 1 || rep.Failed[0].Ticker != "FAIL" || rep.Failed[0].Reason != "boom" || rep.Failed[0].DateRange != "...2025-06-01" {
+		t.Errorf("failed = %+v, want FAIL over the whole history", rep.Failed)
+	}
+	// Progress moves only for the tickers that were fetched and saved.
+	moved := make(map[string]string)
+	for u := range updates {
+		if u.Timeframe != actionsTimeframe {
+			t.Errorf("update %+v is not an actions update", u)
+		}
+		moved[u.Ticker] = u.Date
+	}
+	if len(moved) != 2 || moved["AAPL"] != "2025-06-01" || moved["MSFT"] != "2025-06-01" {
+		t.Errorf("progress updates = %v, want AAPL and MSFT to 2025-06-01", moved)
+	}
+
+	// Raw and adjusted series share one fetch; other classes are not fetched.
+	if len(af.from) != 3 || af.saved["AAPL"] != 2 {
+		t.Errorf("fetched %v, saved %v", af.from, af.saved)
+	}
+	if af.dirs["AAPL"] != "actions" || af.dirs["MSFT"] != "data" {
+		t.Errorf("saved into %v, want AAPL into its ActionsDir, MSFT next to its bars", af.dirs)
+	}
+	if !af.from["AAPL"].IsZero() || !af.from["MSFT"].Equal(time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("from = %v, want the whole history for AAPL, the day after progress for MSFT", af.from)
+	}
+}
@@ -0,0 +1,122 @@
+package crawl
+
+import (
+	"sync"
+	"time"
+
+	"us-data/internal/model"
+)
+
+// chunkAssembler reassembles chunk-level sub-jobs into one outcome per parent
+// job. Chunks of the same ticker may be fetched by different workers in any
+// order; the assembler buffers out-of-order chunks and flushes the contiguous
+// prefix as soon as it grows, so files and progress always advance in range
+// order (a crash loses at most the buffered chunks). Backward jobs number
+// their chunks newest first, so the same prefix rule grows their coverage
+// downward from the earliest covered date.
+type chunkAssembler struct {
+	mu     sync.Mutex
+	groups map[string]*chunkGroup
+}
+
+type chunkGroup struct {
+	mu      sync.Mutex // serializes flushes so progress updates stay ordered
+	parent  Job
+	parts   []*chunkPart
+	next    int // index of the first chunk not yet flushed
+	pending int
+	total   int       // bars flushed so far
+	covered time.Time // edge of the last flushed chunk (see Job.edge)
+	err     error     // first chunk error; the rest of the group is discarded
+}
+
+type chunkPart struct {
+	job  Job
+	bars []model.Bar
+}
+
+func newChunkAssembler() *chunkAssembler {
+	return &chunkAssembler{groups: make(map[string]*chunkGroup)}
+}
+
+// chunkGroupKey identifies the parent job of a chunk.
+func chunkGroupKey(job Job) string {
+	return jobProgressKey(job) + "|" +
+		job.SpanFrom.Format("2006-01-02") + ".." + job.SpanTo.Format("2006-01-02")
+}
+
+// add records the outcome of one chunk and calls flush, in range order, for
+// every chunk that has become part of the contiguous completed prefix.
+//
+// done is true only for the call that accounts for the last chunk of the
+// group; total is then the number of bars flushed, covered the edge of the
+// flushed prefix and err the first chunk error, if any. Chunks after a failed
+// one are never flushed. A flush error (the chunk could not be saved) fails
+// the group like a fetch error, at that chunk.
+func (a *chunkAssembler) add(job Job, bars []model.Bar, err error, flush func(part Job, bars []model.Bar) error) (total int, covered time.Time, firstErr error, done bool) {
+	key := chunkGroupKey(job)
+	a.mu.Lock()
+	g, ok := a.groups[key]
+	if !ok {
+		g = &chunkGroup{parent: job.parent(), parts: make([]*chunkPart, job.Parts), pending: job.Parts}
+		a.groups[key] = g
+	}
+	a.mu.Unlock()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err != nil {
+		g.fail(err)
+	}
+	if g.err == nil && job.Part >= 0 && job.Part < len(g.parts) {
+		g.parts[job.Part] = &chunkPart{job: job, bars: bars}
+		for g.next < len(g.parts) && g.parts[g.next] != nil {
+			p := g.parts[g.next]
+			if err := flush(p.job, p.bars); err != nil {
+				g.fail(err)
+				break
+			}
+			g.total += len(p.bars)
+			g.covered = p.job.edge()
+			g.parts[g.next] = nil
+			g.next++
+		}
+	}
+
+	g.pending--
+	if g.pending > 0 {
+		return 0, time.Time{}, nil, false
+	}
+	a.mu.Lock()
+	delete(a.groups, key)
+	a.mu.Unlock()
+	return g.total, g.covered, g.err, true
+}
+
+// abandon removes and returns the groups still waiting for chunks. Once the
+// workers have stopped, these are groups whose remaining chunks were never
+// fetched because the cycle was cancelled: their flushed prefix is on disk
+// and checkpointed, the rest must be reported as uncovered.
+func (a *chunkAssembler) abandon() []*chunkGroup {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var left []*chunkGroup
+	for key, g := range a.groups {
+		left = append(left, g)
+		delete(a.groups, key)
+	}
+	return left
+}
+
+// fail records the first error of the group and releases the buffered
+// chunks after the flushed prefix; they will be refetched.
+func (g *chunkGroup) fail(err error) {
+	if g.err != nil {
+		return
+	}
+	g.err = err
+	for i := g.next; i < len(g.parts); i++ {
+		g.parts[i] = nil
+	}
+}
@@ -0,0 +1,123 @@
+package crawl
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"us-data/internal/model"
+)
+
+// chunks splits a parent job into n one-day chunks starting at from, the way
+// splitJob numbers them (newest first for backward jobs).
+func chunks(parent Job, from string, n int) []Job {
+	out := make([]Job, n)
+	for i := range n {
+		d := day(from).AddDate(0, 0, i)
+		c := parent
+		c.From, c.To = d, d.Add(24*time.Hour-time.Millisecond)
+		c.Part, c.Parts = i, n
+		c.SpanFrom, c.SpanTo = day(from), endOf(day(from).AddDate(0, 0, n-1).Format("2006-01-02"))
+		out[i] = c
+	}
+	if parent.Backward {
+		slices.Reverse(out)
+		for i := range out {
+			out[i].Part = i
+		}
+	}
+	return out
+}
+
+func bars(n int) []model.Bar { return make([]model.Bar, n) }
+
+func TestChunkAssembler(t *testing.T) {
+	fwd := Job{Class: AssetCrypto, Ticker: "X:BTCUSD", Timeframe: "5min"}
+	bwd := fwd
+	bwd.Backward = true
+	boom := errors.New("boom")
+
+	type event struct {
+		part int
+		err  error
+	}
+	tests := []struct {
+		name        string
+		parent      Job
+		events      []event // arrival order of the chunk outcomes
+		wantFlushed []int   // parts flushed, in order
+		wantCovered string  // edge of the flushed prefix; "" = none
+		wantErr     error
+	}{
+		{"in order", fwd, []event{{0, nil}, {1, nil}, {2, nil}}, []int{0, 1, 2}, "2024-06-03", nil},
+		{"out of order", fwd, []event{{2, nil}, {0, nil}, {1, nil}}, []int{0, 1, 2}, "2024-06-03", nil},
+		{"reversed", fwd, []event{{2, nil}, {1, nil}, {0, nil}}, []int{0, 1, 2}, "2024-06-03", nil},
+		{"backward covers downward", bwd, []event{{1, nil}, {2, nil}, {0, nil}}, []int{0, 1, 2}, "2024-06-01", nil},
+		{"middle fails", fwd, []event{{0, nil}, {2, nil}, {1, boom}}, []int{0}, "2024-06-01", boom},
+		{"first fails", fwd, []event{{1, nil}, {0, boom}, {2, nil}}, nil, "", boom},
+		{"chunks arriving after a failure are dropped", fwd, []event{{1, boom}, {0, nil}, {2, nil}}, nil, "", boom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newChunkAssembler()
+			parts := chunks(tt.parent, "2024-06-01", len(tt.events))
+			var flushed []int
+			flush := func(part Job, b []model.Bar) error {
+				flushed = append(flushed, part.Part)
+				if len(b) != part.Part+1 {
+					t.Errorf("part %d flushed with %d bars", part.Part, len(b))
+				}
+				return nil
+			}
+			for i, e := range tt.events {
+				total, covered, err, done := a.add(parts[e.part], bars(e.part+1), e.err, flush)
+				if last := i == len(tt.events)-1; done != last {
+					t.Fatalf("event %d: done = %v, want %v", i, done, last)
+				}
+				if !done {
+					continue
+				}
+				want := 0
+				for _, p := range tt.wantFlushed {
+					want += p + 1
+				}
+				if total != want {
+					t.Errorf("total = %d, want %d", total, want)
+				}
+				got := ""
+				if !covered.IsZero() {
+					got = covered.Format("2006-01-02")
+				}
+				if got != tt.wantCovered {
+					t.Errorf("covered = %q, want %q", got, tt.wantCovered)
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+			}
+			if !slices.Equal(flushed, tt.wantFlushed) {
+				t.Errorf("flushed %v, want %v", flushed, tt.wantFlushed)
+			}
+			if len(a.groups) != 0 {
+				t.Errorf("%d groups left after the last chunk", len(a.groups))
+			}
+		})
+	}
+}
+
+func TestChunkAssemblerSeparatesParents(t *testing.T) {
+	a := newChunkAssembler()
+	flush := func(Job, []model.Bar) error { return nil }
+	aapl := chunks(Job{Class: AssetCrypto, Ticker: "AAPL"}, "2024-06-01", 2)
+	msft := chunks(Job{Class: AssetCrypto, Ticker: "MSFT"}, "2024-06-01", 2)
+
+	for _, c := range []Job{aapl[0], msft[1], aapl[1]} {
+		if _, _, _, done := a.add(c, nil, nil, flush); done != (c.Ticker == "AAPL" && c.Part == 1) {
+			t.Fatalf("%s part %d: done = %v", c.Ticker, c.Part, done)
+		}
+	}
+	if _, _, _, done := a.add(msft[0], nil, nil, flush); !done {
+		t.Error("MSFT not done after both chunks")
+	}
+}
@@ -0,0 +1,63 @@
+package crawl
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"us-data/internal/ctxutil"
+	"us-data/internal/fsutil"
+)
+
+// diskCheckInterval is how often paused writers check free disk space again.
+const diskCheckInterval = time.Minute
+
+// diskGuard pauses writers while the filesystem holding dir has less than
+// min bytes free. A full disk would otherwise fail every save of the cycle
+// (or leave truncated files); waiting lets an operator free space, or a
+// shutdown stop the run, without losing fetched ranges to retries.
+type diskGuard struct {
+	dir    string
+	min    uint64
+	paused atomic.Bool // logged once per low-space episode, not per worker
+
+	// free and interval replace fsutil.FreeSpace and diskCheckInterval
+	// when set (tests).
+	free     func(dir string) (uint64, error)
+	interval time.Duration
+}
+
+// wait blocks until enough space is free or ctx is cancelled. Platforms or
+// paths whose free space is unknown are never paused.
+func (g *diskGuard) wait(ctx context.Context, logs chan<- LogEntry) error {
+	if g == nil || g.min == 0 {
+		return nil
+	}
+	freeSpace, interval := g.free, g.interval
+	if freeSpace == nil {
+		freeSpace = fsutil.FreeSpace
+	}
+	if interval <= 0 {
+		interval = diskCheckInterval
+	}
+	for {
+		free, err := freeSpace(g.dir)
+		if err != nil || free >= g.min {
+			if err == nil && g.paused.CompareAndSwap(true, false) {
+				logs <- LogEntry{slog.LevelInfo, "disk space recovered, writes resumed", []any{
+					"dir", g.dir, "free_mb", free >> 20,
+				}}
+			}
+			return nil
+		}
+		if g.paused.CompareAndSwap(false, true) {
+			logs <- LogEntry{slog.LevelWarn, "disk space low, writes paused", []any{
+				"dir", g.dir, "free_mb", free >> 20, "min_mb", g.min >> 20,
+			}}
+		}
+		if err := ctxutil.Sleep(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
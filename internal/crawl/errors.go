@@ -0,0 +1,81 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// Retryable is implemented by provider errors that know whether the same
+// request may succeed later (e.g. 429 or 5xx) or will always fail (404, 403).
+type Retryable interface {
+	Retryable() bool
+}
+
+// Kinded is implemented by provider errors that expose a short,
+// machine-readable kind such as "rate_limited" or "not_found".
+type Kinded interface {
+	ErrorKind() string
+}
+
+// Uncovered is implemented by provider errors that report which part of the
+// requested range was not fetched (e.g. a chunk Polygon returned as DELAYED).
+type Uncovered interface {
+	UncoveredRange() (from, to time.Time)
+}
+
+// uncoveredRange extracts the unfetched range from err, if it reports one.
+func uncoveredRange(err error) (from, to time.Time, ok bool) {
+	var u Uncovered
+	if !errors.As(err, &u) {
+		return time.Time{}, time.Time{}, false
+	}
+	from, to = u.UncoveredRange()
+	return from, to, true
+}
+
+// saveError is a failure to write fetched bars (disk full, permissions). The
+// data was fetched but not kept, so progress must not move past it; the range
+// is retried like any transient failure.
+type saveError struct{ err error }
+
+func (e *saveError) Error() string     { return "save: " + e.err.Error() }
+func (e *saveError) Unwrap() error     { return e.err }
+func (e *saveError) Retryable() bool   { return true }
+func (e *saveError) ErrorKind() string { return "save_error" }
+
+// isTransient classifies a FetchBars error for the in-cycle retry stage.
+//
+// Errors implementing Retryable decide for themselves; network errors are
+// transient. Anything else is treated as permanent so an unknown failure mode
+// never burns the rate-limit budget in a retry loop.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var r Retryable
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+// errorKind returns the kind reported in logs and .lastrun.failed.json.
+func errorKind(err error) string {
+	var k Kinded
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &k):
+		return k.ErrorKind()
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "cancelled"
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return "network"
+	}
+	return "unknown"
+}
@@ -0,0 +1,55 @@
+package crawl
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"us-data/internal/calendar"
+	"us-data/internal/model"
+)
+
+// sessionGaps checks bars fetched for [from, to] against cal and returns the
+// trading days that have no bar at all, plus the number of bars the sessions
+// could hold at the given interval.
+//
+// Gap detection needs intraday or daily bars; for longer intervals (or an
+// unknown timeframe) it returns nothing.
+func sessionGaps(cal calendar.Calendar, from, to time.Time, interval time.Duration, bars []model.Bar) (missing []time.Time, expected int) {
+	if interval <= 0 || interval > 24*time.Hour {
+		return nil, 0
+	}
+	seen := make(map[time.Time]bool)
+	for _, b := range bars {
+		seen[calendar.DayOf(cal, time.UnixMilli(b.Timestamp))] = true
+	}
+	for _, d := range calendar.TradingDays(cal, from, to) {
+		expected += calendar.ExpectedBars(cal, d, interval)
+		if !seen[d] {
+			missing = append(missing, d)
+		}
+	}
+	return missing, expected
+}
+
+// timeframeInterval parses a timeframe label ("5min", "1h", "1d") into the bar
+// interval. Week and month bars have no fixed length and return 0.
+func timeframeInterval(tf string) time.Duration {
+	i := strings.IndexFunc(tf, func(r rune) bool { return r < '0' || r > '9' })
+	if i <= 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(tf[:i])
+	if err != nil || n <= 0 {
+		return 0
+	}
+	switch tf[i:] {
+	case "min":
+		return time.Duration(n) * time.Minute
+	case "h":
+		return time.Duration(n) * time.Hour
+	case "d":
+		return time.Duration(n) * 24 * time.Hour
+	}
+	return 0
+}
@@ -1,20 +1,121 @@
 package crawl
 
 import (
+	"context"
 	"time"
 
+	"us-data/internal/calendar"
 	"us-data/internal/model"
 )
 
+// ChunkFunc receives the bars of one completed request window [from, to].
+// Returning an error aborts the fetch.
+type ChunkFunc func(from, to time.Time, bars []model.Bar) error
+
 // BarFetcher is the only interface the crawl engine depends on from the provider layer.
 //
 // DIP: crawl (high-level) defines this interface; providers (low-level) implement it.
 // Runner never imports a concrete provider — it depends only on this abstraction.
 type BarFetcher interface {
 	// FetchBars retrieves minute OHLCV bars for one instrument over [from, to].
-	FetchBars(ticker, apiKey string, from, to time.Time) ([]model.Bar, error)
+	// Implementations must return promptly (with ctx.Err()) once ctx is cancelled,
+	// including while sleeping between rate-limited requests.
+	//
+	// When onChunk is non-nil, bars are delivered chunk by chunk (in range order)
+	// through it instead of being returned, and the returned slice is nil.
+	//
+	// adj selects split-adjusted or raw (unadjusted) prices. cal is the
+	// ticker's trading calendar: requests are trimmed to its sessions with the
+	// same rule as the Runner's ranges (see calendar.Trim).
+	FetchBars(ctx context.Context, ticker, apiKey string, adj Adjustment, cal calendar.Calendar, from, to time.Time, onChunk ChunkFunc) ([]model.Bar, error)
+
+	// SaveBars persists bars under the ticker's directory in dir (see
+	// Job.TickerDir) using the configured storage format. dir is the
+	// asset-class-specific directory (e.g. data/Polygon/stocks).
+	// Raw bars get file names distinct from adjusted ones. File names of a
+	// series, at any depth under the ticker directory, must start with
+	// {ticker}_{timeframe}_ (escaped ticker, timeframe as in Job.Timeframe):
+	// a split rewrite replaces exactly those files.
+	// An error means the bars were not kept; the Runner does not advance
+	// progress past them.
+	//
+	// partition is the Runner's (see Runner.Partition): "" or PartitionNone
+	// saves one file named after [from, to]; day, month or year saves bars
+	// that all fall into from's period into that period's file, merged with
+	// the bars already in it.
+	SaveBars(dir, ticker string, adj Adjustment, partition Partition, from, to time.Time, bars []model.Bar) error
+
+	// OpenBars starts one bar file for [from, to], named and placed as by an
+	// unpartitioned SaveBars, that chunks are appended to as they are fetched. It becomes
+	// complete on Commit; Abort leaves nothing behind.
+	OpenBars(dir, ticker string, adj Adjustment, from, to time.Time) (BarWriter, error)
+}
+
+// BarWriter streams bars into one file (see BarFetcher.OpenBars). Exactly
+// one of Commit and Abort must be called.
+type BarWriter interface {
+	Append(bars []model.Bar) error
+	Commit() error
+	Abort() error
+}
+
+// ChunkPlanner is optionally implemented by a BarFetcher that splits requests
+// into fixed-size date windows. When available and data.splitJobs is on,
+// app.Run sets Runner.ChunkDays from it, so the producer emits chunk-level
+// sub-jobs that any worker in the key pool can pick up.
+type ChunkPlanner interface {
+	// MaxDaysPerChunk returns the largest calendar-day window of one request.
+	MaxDaysPerChunk() int
+}
+
+// LifetimeSource is optionally implemented by a BarFetcher that knows when a
+// ticker started and stopped trading (list and delisting dates). When
+// available, the producer clamps backfill ranges to that lifetime instead of
+// requesting years of empty chunks for a recent IPO.
+type LifetimeSource interface {
+	// TickerLifetime returns the lifetime of ticker; zero bounds are unknown.
+	// Implementations should cache: it is called for every long range.
+	TickerLifetime(ctx context.Context, ticker, apiKey string) (Lifetime, error)
+}
+
+// ActionFetcher is optionally implemented by a BarFetcher that can fetch
+// corporate actions (splits, dividends). When available, the Runner fetches
+// them for Runner.ActionClasses after the bar jobs of each cycle.
+type ActionFetcher interface {
+	// FetchActions returns the actions of ticker effective in [from, to]
+	// (execution / ex-dividend date), sorted by date; a zero from means
+	// the whole history.
+	FetchActions(ctx context.Context, ticker, apiKey string, from, to time.Time) ([]model.CorporateAction, error)
+
+	// SaveActions merges actions into the ticker's actions file in
+	// dir/ticker/ (dir is the target's ActionsDir), in the configured storage
+	// format: one file per ticker, an action replacing a saved one with the
+	// same ID.
+	// An error leaves progress unchanged.
+	SaveActions(dir, ticker string, actions []model.CorporateAction) error
+}
+
+// SplitSource is optionally implemented by a BarFetcher that lists stock
+// splits market-wide. When available, the Runner checks it every cycle and
+// refetches the adjusted history a new split invalidated (see detectSplits).
+type SplitSource interface {
+	// Splits returns the splits executed in [from, to], any ticker.
+	Splits(ctx context.Context, apiKey string, from, to time.Time) ([]model.CorporateAction, error)
+}
 
-	// SaveBars persists bars to dir/ticker/ using the configured storage format.
-	// dir is the asset-class-specific directory (e.g. data/Polygon/stocks).
-	SaveBars(dir, ticker string, from, to time.Time, bars []model.Bar)
+// ProgressStore persists the last fetched date per crawl identity
+// (progressKey → "YYYY-MM-DD"). Implementations must make every Put durable
+// and atomic: a crash may lose a batch but never corrupt the store.
+type ProgressStore interface {
+	// Load returns a snapshot of all entries. An error means the store could
+	// not be read and must not be treated as empty.
+	Load() (map[string]string, error)
+	// Put upserts a batch of entries in a single write. Coverage never
+	// shrinks: an entry only replaces a stored date it moves outward, later
+	// for last-day and stage keys, earlier for #earliest keys. A stored value
+	// that is not a date is always replaced.
+	Put(entries map[string]string) error
+	// Delete removes entries; missing keys are ignored.
+	Delete(keys []string) error
+	Close() error
 }
@@ -1,29 +1,29 @@
 package crawl
 
 import (
-	"path/filepath"
+	"slices"
 	"sort"
 	"time"
-)
 
-// ClassSaveDir returns the per-class subdirectory under saveBaseDir.
-//
-//	ClassSaveDir("data/Polygon", AssetStocks) → "data/Polygon/stocks"
-func ClassSaveDir(saveBaseDir string, class AssetClass) string {
-	return filepath.Join(saveBaseDir, string(class))
-}
+	"us-data/internal/calendar"
+	"us-data/internal/layout"
+)
 
 // BuildTargets stamps a flat ticker list into typed Job targets,
-// filling Source, Class, and SaveDir for each entry.
-func BuildTargets(tickers []string, saveBaseDir, source string, class AssetClass) []Job {
-	dir := ClassSaveDir(saveBaseDir, class)
+// filling Source, Class, Timeframe, Adjustment, SaveDir and TickerDir for
+// each entry. saveDir is the class directory of the series and l the output
+// layout naming the ticker directories under it.
+func BuildTargets(tickers []string, saveDir string, l *layout.Layout, source string, class AssetClass, timeframe string, adj Adjustment) []Job {
 	out := make([]Job, 0, len(tickers))
 	for _, t := range tickers {
 		out = append(out, Job{
-			Source:  source,
-			Class:   class,
-			Ticker:  t,
-			SaveDir: dir,
+			Source:     source,
+			Class:      class,
+			Ticker:     t,
+			Timeframe:  timeframe,
+			SaveDir:    saveDir,
+			TickerDir:  l.TickerDir(t),
+			Adjustment: adj,
 		})
 	}
 	return out
@@ -36,35 +36,112 @@ func BuildTargets(tickers []string, saveBaseDir, source string, class AssetClass
 //   - no progress entry → backfill backfillYears of history ending yesterday
 //   - has entry         → fetch from lastday+1 to yesterday
 //   - already up to date → skip=true
+//   - no trading session in the range (weekend, holiday) → skip=true
+//
+// The range is trimmed to the extended sessions of the target's calendar
+// (see calendar.Trim), so a job never starts or ends on a closed day; a
+// closed day still keeps the post-market tail of the session before it.
 //
 // Chunk splitting and rate-limiting are handled inside CrawlBarsWithKey.
 func resolveJobRange(target Job, m map[string]string, now time.Time, backfillYears int) (from, to time.Time, skip bool) {
 	yesterday := date(now).AddDate(0, 0, -1)
 	endOfYesterday := yesterday.Add(24*time.Hour - time.Millisecond)
 
-	key := progressKey(target.Source, target.Class, target.Ticker)
-	last, ok := m[key]
+	// Old key formats are upgraded by BootstrapProgress; only the timeframe
+	// key counts here, so a new resolution never inherits another's progress.
+	last, ok := m[jobProgressKey(target)]
+
 	if !ok {
-		if legacy, legacyOk := m[target.Ticker]; legacyOk {
-			last = legacy
-			ok = true
+		from = backfillHorizon(now, backfillYears)
+	} else {
+		lastDay, _ := time.ParseInLocation("2006-01-02", last, time.UTC)
+		from = date(lastDay).AddDate(0, 0, 1)
+		if from.After(yesterday) {
+			return time.Time{}, time.Time{}, true // already up to date
 		}
 	}
 
+	from, to, ok = calendar.Trim(target.tradingCalendar(), from, endOfYesterday)
 	if !ok {
-		if backfillYears <= 0 {
-			backfillYears = 2
-		}
-		from = time.Date(now.Year()-backfillYears, now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-		return from, endOfYesterday, false
+		return time.Time{}, time.Time{}, true // only closed days since last run
 	}
+	return from, to, false
+}
 
-	lastDay, _ := time.ParseInLocation("2006-01-02", last, time.UTC)
-	startDate := date(lastDay).AddDate(0, 0, 1)
-	if !startDate.Before(yesterday) && !startDate.Equal(yesterday) {
-		return time.Time{}, time.Time{}, true // already up to date
+// resolveBackfillRange computes the backward extension still needed for
+// target: from the backfill horizon (now − backfillYears) to the day before
+// the earliest covered date. skip is true when coverage already reaches the
+// horizon, i.e. unless data.backfillYears was raised since the ticker was
+// first crawled.
+func resolveBackfillRange(target Job, m map[string]string, now time.Time, backfillYears int) (from, to time.Time, skip bool) {
+	first, ok := m[earliestKey(jobProgressKey(target))]
+	if !ok {
+		return time.Time{}, time.Time{}, true // not bootstrapped yet
+	}
+	firstDay, err := time.ParseInLocation("2006-01-02", first, time.UTC)
+	if err != nil {
+		return time.Time{}, time.Time{}, true
+	}
+	from = backfillHorizon(now, backfillYears)
+	if !from.Before(firstDay) {
+		return time.Time{}, time.Time{}, true
 	}
-	return startDate, endOfYesterday, false
+	from, to, ok = calendar.Trim(target.tradingCalendar(), from, firstDay.Add(-time.Millisecond))
+	if !ok {
+		return time.Time{}, time.Time{}, true
+	}
+	return from, to, false
+}
+
+// backfillHorizon returns the first day of a backfillYears history ending now
+// (default 2 years).
+func backfillHorizon(now time.Time, backfillYears int) time.Time {
+	if backfillYears <= 0 {
+		backfillYears = 2
+	}
+	return time.Date(now.Year()-backfillYears, now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// splitJob splits a resolved job into chunk-level sub-jobs of at most
+// chunkDays calendar days each. The boundaries match the provider's own
+// chunking, so a chunk is exactly one API request; like the provider, chunks
+// are trimmed to sessions and chunks without one are dropped. Backward jobs
+// are numbered newest chunk first. Returns the job unchanged when
+// chunkDays <= 0 or the range fits in a single chunk.
+func splitJob(job Job, chunkDays int) []Job {
+	if chunkDays <= 0 || job.Rewrite != "" {
+		return []Job{job}
+	}
+	cal := job.tradingCalendar()
+	var ranges [][2]time.Time
+	for start := job.From; !start.After(job.To); {
+		end := date(start).AddDate(0, 0, chunkDays).Add(-time.Millisecond) // end of the chunk's last day
+		if end.After(job.To) {
+			end = job.To
+		}
+		if from, to, ok := calendar.Trim(cal, start, end); ok {
+			ranges = append(ranges, [2]time.Time{from, to})
+		}
+		if end.Equal(job.To) {
+			break
+		}
+		start = date(end).AddDate(0, 0, 1)
+	}
+	if len(ranges) <= 1 {
+		return []Job{job}
+	}
+	if job.Backward {
+		slices.Reverse(ranges) // newest chunk first: coverage grows down from the earliest date
+	}
+	out := make([]Job, 0, len(ranges))
+	for i, r := range ranges {
+		sub := job
+		sub.From, sub.To = r[0], r[1]
+		sub.Part, sub.Parts = i, len(ranges)
+		sub.SpanFrom, sub.SpanTo = job.From, job.To
+		out = append(out, sub)
+	}
+	return out
 }
 
 // ---------------------------------------------------------------------------
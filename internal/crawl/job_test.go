@@ -0,0 +1,214 @@
+package crawl
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"us-data/internal/layout"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func endOf(s string) time.Time { return day(s).Add(24*time.Hour - time.Millisecond) }
+
+// dates formats the From..To days of jobs, e.g. "2024-06-03..2024-06-07".
+func dates(jobs []Job) []string {
+	out := make([]string, len(jobs))
+	for i, j := range jobs {
+		out[i] = j.From.Format("2006-01-02") + ".." + j.To.Format("2006-01-02")
+	}
+	return out
+}
+
+func TestSplitJob(t *testing.T) {
+	stocks := Job{Class: AssetStocks, Ticker: "AAPL", Timeframe: "5min"}
+	crypto := Job{Class: AssetCrypto, Ticker: "X:BTCUSD", Timeframe: "5min"}
+	with := func(j Job, from, to string, f func(*Job)) Job {
+		j.From, j.To = day(from), endOf(to)
+		if f != nil {
+			f(&j)
+		}
+		return j
+	}
+	backward := func(j *Job) { j.Backward = true }
+
+	tests := []struct {
+		name      string
+		job       Job
+		chunkDays int
+		want      []string // chunk ranges, in Part order; nil = job returned unchanged
+	}{
+		{"weekly chunks trimmed to sessions", with(stocks, "2024-06-03", "2024-06-28", nil), 7,
+			[]string{"2024-06-03..2024-06-07", "2024-06-10..2024-06-14", "2024-06-17..2024-06-21", "2024-06-24..2024-06-28"}},
+		{"weekend-only chunk dropped", with(stocks, "2024-06-06", "2024-06-11", nil), 2,
+			[]string{"2024-06-06..2024-06-07", "2024-06-10..2024-06-11"}},
+		{"crypto keeps weekends", with(crypto, "2024-06-06", "2024-06-11", nil), 3,
+			[]string{"2024-06-06..2024-06-08", "2024-06-09..2024-06-11"}},
+		{"backward numbered newest first", with(stocks, "2024-06-03", "2024-06-21", backward), 7,
+			[]string{"2024-06-17..2024-06-21", "2024-06-10..2024-06-14", "2024-06-03..2024-06-07"}},
+		{"fits in one chunk", with(stocks, "2024-06-03", "2024-06-07", nil), 7, nil},
+		{"one session left after trimming", with(stocks, "2024-06-07", "2024-06-09", nil), 1, nil},
+		{"chunking disabled", with(stocks, "2024-06-03", "2024-06-28", nil), 0, nil},
+		{"rewrite never split", with(stocks, "2024-06-03", "2024-06-28", func(j *Job) { j.Rewrite = "2024-06-10" }), 7, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitJob(tt.job, tt.chunkDays)
+			if tt.want == nil {
+				if len(got) != 1 || !got[0].From.Equal(tt.job.From) || !got[0].To.Equal(tt.job.To) || got[0].isChunk() {
+					t.Fatalf("splitJob = %v, want the job unchanged", dates(got))
+				}
+				return
+			}
+			if d := dates(got); !slices.Equal(d, tt.want) {
+				t.Fatalf("splitJob = %v, want %v", d, tt.want)
+			}
+			for i, c := range got {
+				if c.Part != i || c.Parts != len(tt.want) {
+					t.Errorf("chunk %d: Part/Parts = %d/%d", i, c.Part, c.Parts)
+				}
+				if !c.SpanFrom.Equal(tt.job.From) || !c.SpanTo.Equal(tt.job.To) {
+					t.Errorf("chunk %d: span = %v..%v, want the parent range", i, c.SpanFrom, c.SpanTo)
+				}
+				if p := c.parent(); !p.From.Equal(tt.job.From) || !p.To.Equal(tt.job.To) || p.isChunk() {
+					t.Errorf("chunk %d: parent = %+v", i, p)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitJobChunksAreContiguous(t *testing.T) {
+	job := Job{Class: AssetCrypto, Ticker: "X:BTCUSD", From: day("2024-06-01"), To: endOf("2024-06-10")}
+	got := splitJob(job, 3)
+	if len(got) != 4 {
+		t.Fatalf("splitJob = %v, want 4 chunks", dates(got))
+	}
+	for i := 1; i < len(got); i++ {
+		if !got[i-1].To.Add(time.Millisecond).Equal(got[i].From) {
+			t.Errorf("chunk %d ends at %v, chunk %d starts at %v", i-1, got[i-1].To, i, got[i].From)
+		}
+	}
+}
+
+func TestResolveJobRangeKeepsPostMarket(t *testing.T) {
+	l, _ := layout.New(layout.Default)
+	target := BuildTargets([]string{"AAPL"}, "data", l, "massive", AssetStocks, "5min", Adjusted)[0]
+	key := jobProgressKey(target)
+
+	// Friday 2024-01-05 post-market runs until 01:00 UTC on Saturday.
+	from, to, skip := resolveJobRange(target, map[string]string{key: "2024-01-05"}, day("2024-01-07").Add(10*time.Hour), 2)
+	if skip || !from.Equal(day("2024-01-06")) || !to.Equal(day("2024-01-06").Add(time.Hour-time.Millisecond)) {
+		t.Errorf("winter weekend = %v..%v skip=%v, want 2024-01-06 00:00..00:59:59.999", from, to, skip)
+	}
+	// In summer it ends at 00:00 UTC: nothing to fetch until Monday.
+	if from, to, skip := resolveJobRange(target, map[string]string{key: "2024-06-14"}, day("2024-06-16").Add(10*time.Hour), 2); !skip {
+		t.Errorf("summer weekend = %v..%v, want skip", from, to)
+	}
+}
+
+func TestJobRemaining(t *testing.T) {
+	fwd := Job{From: day("2024-06-03"), To: endOf("2024-06-28")}
+	bwd := fwd
+	bwd.Backward = true
+
+	tests := []struct {
+		name    string
+		job     Job
+		covered time.Time
+		want    string
+	}{
+		{"forward nothing covered", fwd, time.Time{}, "2024-06-03..2024-06-28"},
+		{"forward resumes the day after", fwd, endOf("2024-06-14"), "2024-06-15..2024-06-28"},
+		{"forward fully covered keeps the job", fwd, endOf("2024-06-28"), "2024-06-03..2024-06-28"},
+		{"backward nothing covered", bwd, time.Time{}, "2024-06-03..2024-06-28"},
+		{"backward ends the day before", bwd, day("2024-06-17"), "2024-06-03..2024-06-16"},
+		{"backward fully covered keeps the job", bwd, day("2024-06-03"), "2024-06-03..2024-06-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.job.remaining(tt.covered)
+			if got := dates([]Job{r})[0]; got != tt.want {
+				t.Errorf("remaining = %s, want %s", got, tt.want)
+			}
+			if tt.job.Backward && r.To.Before(tt.job.To) && !r.To.Equal(tt.covered.Add(-time.Millisecond)) {
+				t.Errorf("backward remaining ends at %v, want the end of the previous day", r.To)
+			}
+		})
+	}
+}
+
+func TestEmptyPolicyComplete(t *testing.T) {
+	week := Job{Class: AssetStocks, From: day("2024-06-03"), To: endOf("2024-06-07")}    // 5 sessions
+	month := Job{Class: AssetStocks, From: day("2024-06-03"), To: endOf("2024-06-28")}   // 19 sessions
+	weekend := Job{Class: AssetStocks, From: day("2024-06-08"), To: endOf("2024-06-09")} // none
+	backward := month
+	backward.Backward = true
+	rewrite := week
+	rewrite.Rewrite = "2024-06-05"
+
+	tests := []struct {
+		name   string
+		policy EmptyPolicy
+		job    Job
+		want   bool
+	}{
+		{"auto short range", EmptyPolicy{Mode: EmptyAuto, MaxTradingDays: 5}, week, true},
+		{"auto long range", EmptyPolicy{Mode: EmptyAuto, MaxTradingDays: 5}, month, false},
+		{"auto counts sessions, not days", EmptyPolicy{Mode: EmptyAuto, MaxTradingDays: 1}, weekend, true},
+		{"auto default threshold", EmptyPolicy{}, week, true},
+		{"auto raised threshold", EmptyPolicy{Mode: EmptyAuto, MaxTradingDays: 20}, month, true},
+		{"complete", EmptyPolicy{Mode: EmptyComplete}, month, true},
+		{"fail", EmptyPolicy{Mode: EmptyFail}, week, false},
+		{"backward reached the start of history", EmptyPolicy{Mode: EmptyFail}, backward, true},
+		{"rewrite never", EmptyPolicy{Mode: EmptyComplete}, rewrite, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.complete(tt.job); got != tt.want {
+				t.Errorf("complete = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLifetimeClamp(t *testing.T) {
+	from, to := day("2024-06-03"), endOf("2024-06-28")
+	tests := []struct {
+		name string
+		life Lifetime
+		want string // "" = outside the lifetime
+	}{
+		{"unknown", Lifetime{}, "2024-06-03..2024-06-28"},
+		{"listed inside", Lifetime{Listed: day("2024-06-12").Add(13 * time.Hour)}, "2024-06-12..2024-06-28"},
+		{"delisted inside", Lifetime{Delisted: day("2024-06-20")}, "2024-06-03..2024-06-20"},
+		{"both inside", Lifetime{Listed: day("2024-06-10"), Delisted: day("2024-06-14")}, "2024-06-10..2024-06-14"},
+		{"listed before", Lifetime{Listed: day("2020-01-02")}, "2024-06-03..2024-06-28"},
+		{"listed after", Lifetime{Listed: day("2024-07-01")}, ""},
+		{"delisted before", Lifetime{Delisted: day("2024-05-31")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, e, ok := tt.life.clamp(from, to)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("clamp = %v..%v, want outside the lifetime", f, e)
+				}
+				return
+			}
+			if got := dates([]Job{{From: f, To: e}})[0]; !ok || got != tt.want {
+				t.Errorf("clamp = %s ok=%v, want %s", got, ok, tt.want)
+			}
+			if !tt.life.Delisted.IsZero() && !e.Equal(endOf(tt.life.Delisted.Format("2006-01-02"))) {
+				t.Errorf("clamp end = %v, want the end of the delisting day", e)
+			}
+		})
+	}
+}
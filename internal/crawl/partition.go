@@ -0,0 +1,69 @@
+package crawl
+
+import (
+	"time"
+
+	"us-data/internal/calendar"
+	"us-data/internal/model"
+)
+
+// Partition selects how the bars of a series are split into files.
+type Partition string
+
+const (
+	// PartitionNone saves every saved range as its own file, named after it.
+	PartitionNone Partition = "none"
+	// PartitionDay, PartitionMonth and PartitionYear save one file per
+	// trading day, month or year of the bars, in the exchange time zone.
+	// Later saves into the same period merge into its file, so increments
+	// and backfills land in the same predictable files.
+	PartitionDay   Partition = "day"
+	PartitionMonth Partition = "month"
+	PartitionYear  Partition = "year"
+)
+
+// partitioned reports whether p splits bars by period.
+func (p Partition) partitioned() bool {
+	return p == PartitionDay || p == PartitionMonth || p == PartitionYear
+}
+
+// period returns the first day of the period of p holding day.
+func (p Partition) period(day time.Time) time.Time {
+	switch p {
+	case PartitionMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case PartitionYear:
+		return time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// barRange is the bars of one file to save, with the trading days
+// [from, to] they span.
+type barRange struct {
+	from, to time.Time
+	bars     []model.Bar
+}
+
+// split groups bars fetched for part by period of their trading day in cal,
+// keeping their order. Unpartitioned bars are one range covering part.
+func (p Partition) split(cal calendar.Calendar, part Job, bars []model.Bar) []barRange {
+	if !p.partitioned() {
+		return []barRange{{from: part.From, to: part.To, bars: bars}}
+	}
+	var out []barRange
+	start := 0
+	for i := 1; i <= len(bars); i++ {
+		if i < len(bars) && p.period(barDay(cal, bars[i])).Equal(p.period(barDay(cal, bars[start]))) {
+			continue
+		}
+		out = append(out, barRange{from: barDay(cal, bars[start]), to: barDay(cal, bars[i-1]), bars: bars[start:i]})
+		start = i
+	}
+	return out
+}
+
+// barDay returns the trading day of b in cal.
+func barDay(cal calendar.Calendar, b model.Bar) time.Time {
+	return calendar.DayOf(cal, time.UnixMilli(b.Timestamp))
+}
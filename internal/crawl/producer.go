@@ -13,46 +13,166 @@ import (
 // are already up to date. Up-to-date skips never reach the worker.
 //
 // The worker receives a Job with From/To already set and only needs to fetch.
+//
+// When ChunkDays > 0, long ranges are split into chunk-level sub-jobs (one API
+// request each) so several keys can work on the same ticker in parallel; the
+// Runner reassembles them before save and progress update.
+//
+// Forward jobs (new days since the last run) are queued for every target
+// first; backward extension jobs (history older than the earliest covered day
+// after BackfillYears was raised, see resolveBackfillRange) follow, so the
+// daily update never waits behind a long backfill.
+//
+// A range that lies entirely outside its ticker's lifetime (see Lifetimes) is
+// recorded as covered without a job, so a delisted ticker is not looked up
+// again every cycle.
+//
+// A target with a pending split rewrite (see detectSplits) gets a rewrite job
+// over its whole history instead of its forward job, and no backward job
+// until the rewrite has succeeded.
 type ProgressProducer struct {
 	Targets       []Job
-	ProgressPath  string
+	Progress      ProgressStore
 	BackfillYears int // years of history to fetch on first run (default: 2)
+	ChunkDays     int // 0 = one Job per target; >0 = split into sub-jobs of this many days
+
+	// Lifetimes, when set, clamps long ranges to each ticker's list and
+	// delisting dates; lookups are rate limited on APIKey.
+	Lifetimes LifetimeSource
+	APIKey    string
 }
 
+// lifetimeLookupDays is the shortest range worth a lifetime lookup: the daily
+// update of a listed ticker never needs one.
+const lifetimeLookupDays = 31
+
 // NewProgressProducer constructs a ProgressProducer.
-func NewProgressProducer(targets []Job, progressPath string, backfillYears int) *ProgressProducer {
-	return &ProgressProducer{Targets: targets, ProgressPath: progressPath, BackfillYears: backfillYears}
+func NewProgressProducer(targets []Job, progress ProgressStore, backfillYears, chunkDays int) *ProgressProducer {
+	return &ProgressProducer{
+		Targets: targets, Progress: progress,
+		BackfillYears: backfillYears, ChunkDays: chunkDays,
+	}
 }
 
 // Start resolves date ranges for all targets and streams pending Jobs into the
 // returned channel. The channel is closed when all targets are processed or ctx
 // is cancelled. Targets already up to date are silently dropped.
 //
-// The progress file is read once before the loop — not once per target.
+// The progress store is read once before the loop — not once per target.
 func (p *ProgressProducer) Start(ctx context.Context) <-chan Job {
 	out := make(chan Job, 64)
 	go func() {
 		defer close(out)
 		now := time.Now().UTC()
-		m := loadProgress(p.ProgressPath) // single read for all targets
-		pending, skipped := 0, 0
+		m, err := p.Progress.Load() // single read for all targets
+		if err != nil {
+			// Never fall back to an empty map: that would re-backfill everything.
+			slog.Error("producer: progress load failed, no jobs queued", "err", err)
+			return
+		}
+		pending, chunks, skipped, backward, clamped, rewrites := 0, 0, 0, 0, 0, 0
+		rewriting := make(map[string]bool)
+		outside := make(map[string]string) // progress of ranges outside a lifetime
+		defer func() {
+			if err := p.Progress.Put(outside); err != nil {
+				slog.Warn("producer: ranges outside ticker lifetimes not recorded", "err", err)
+			}
+		}()
+		lifetime := func(job Job) (Job, bool) {
+			out, ok := p.clampLifetime(ctx, job)
+			if !ok || !out.From.Equal(job.From) || !out.To.Equal(job.To) {
+				clamped++
+			}
+			return out, ok
+		}
+		emit := func(job Job) bool {
+			for _, sub := range splitJob(job, p.ChunkDays) {
+				select {
+				case out <- sub:
+					chunks++
+				case <-ctx.Done():
+					slog.Info("producer stopped early", "reason", "context cancelled")
+					return false
+				}
+			}
+			pending++
+			return true
+		}
 		for _, target := range p.Targets {
+			if from, to, split, ok := resolveRewriteRange(target, m, now); ok {
+				job, ok := lifetime(withRange(target, from, to, false))
+				if ok {
+					rewriting[jobProgressKey(target)] = true
+					job.Rewrite = split
+					if !emit(job) {
+						return
+					}
+					rewrites++
+					continue
+				}
+			}
 			from, to, skip := resolveJobRange(target, m, now, p.BackfillYears)
 			if skip {
 				skipped++
 				continue
 			}
-			job := target
-			job.From, job.To = from, to
-			select {
-			case out <- job:
-				pending++
-			case <-ctx.Done():
-				slog.Info("producer stopped early", "reason", "context cancelled")
+			job, ok := lifetime(withRange(target, from, to, false))
+			if !ok {
+				skipped++ // entirely before listing or after delisting
+				outside[jobProgressKey(target)] = to.Format("2006-01-02")
+				continue
+			}
+			if !emit(job) {
 				return
 			}
 		}
-		slog.Info("jobs queued", "count", pending, "skipped", skipped)
+		for _, target := range p.Targets {
+			from, to, skip := resolveBackfillRange(target, m, now, p.BackfillYears)
+			if skip || rewriting[jobProgressKey(target)] {
+				continue
+			}
+			job, ok := lifetime(withRange(target, from, to, true))
+			if !ok {
+				// History already reaches the list date.
+				outside[earliestKey(jobProgressKey(target))] = from.Format("2006-01-02")
+				continue
+			}
+			if !emit(job) {
+				return
+			}
+			backward++
+		}
+		slog.Info("jobs queued", "count", pending, "chunks", chunks, "skipped", skipped,
+			"backward", backward, "rewrites", rewrites, "lifetime_clamped", clamped)
 	}()
 	return out
 }
+
+// withRange returns target with its range (and direction) set.
+func withRange(target Job, from, to time.Time, backward bool) Job {
+	target.From, target.To, target.Backward = from, to, backward
+	return target
+}
+
+// clampLifetime narrows a long job to its ticker's trading lifetime. ok is
+// false when nothing of the range lies within it. Lookup failures leave the
+// job unchanged: the range is then fetched in full, as without a source.
+func (p *ProgressProducer) clampLifetime(ctx context.Context, job Job) (Job, bool) {
+	if p.Lifetimes == nil || job.To.Sub(job.From) < lifetimeLookupDays*24*time.Hour {
+		return job, true
+	}
+	lt, err := p.Lifetimes.TickerLifetime(ctx, job.Ticker, p.APIKey)
+	if err != nil {
+		slog.Warn("ticker lifetime lookup failed, range not clamped", "ticker", job.Ticker, "err", err)
+		return job, true
+	}
+	from, to, ok := lt.clamp(job.From, job.To)
+	if !ok {
+		slog.Debug("range outside ticker lifetime", "ticker", job.Ticker,
+			"from", job.From.Format("2006-01-02"), "to", job.To.Format("2006-01-02"),
+			"listed", lt.Listed.Format("2006-01-02"), "delisted", lt.Delisted.Format("2006-01-02"))
+		return job, false
+	}
+	job.From, job.To = from, to
+	return job, true
+}
@@ -0,0 +1,90 @@
+package crawl
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"us-data/internal/layout"
+)
+
+// fakeLifetimes is a LifetimeSource serving canned lifetimes and recording
+// the tickers looked up.
+type fakeLifetimes struct {
+	lifetimes map[string]Lifetime
+	asked     []string
+}
+
+func (f *fakeLifetimes) TickerLifetime(_ context.Context, ticker, _ string) (Lifetime, error) {
+	f.asked = append(f.asked, ticker)
+	return f.lifetimes[ticker], nil
+}
+
+// produce runs p to completion and returns the jobs by ticker.
+func produce(p *ProgressProducer) map[string][]Job {
+	jobs := make(map[string][]Job)
+	for j := range p.Start(context.Background()) {
+		jobs[j.Ticker] = append(jobs[j.Ticker], j)
+	}
+	return jobs
+}
+
+func TestProducerClampsAndSkipsByLifetime(t *testing.T) {
+	now := time.Now().UTC()
+	yesterday := date(now).AddDate(0, 0, -1).Format("2006-01-02")
+	horizon := backfillHorizon(now, 2)
+	store := NewJSONProgressStore(filepath.Join(t.TempDir(), ".lastday.json"))
+	if err := store.Put(map[string]string{
+		"massive:crypto:X:BTCUSD@1d":          date(now).AddDate(0, 0, -3).Format("2006-01-02"),
+		"massive:crypto:X:BTCUSD@1d#earliest": horizon.Format("2006-01-02"),
+		"massive:crypto:X:DEAD@1d":            "2024-01-31", // delisted 2024-03-01
+		"massive:crypto:X:DEAD@1d#earliest":   horizon.Format("2006-01-02"),
+		"massive:crypto:X:GONE@1d":            "2024-01-31", // delisted before it
+		"massive:crypto:X:GONE@1d#earliest":   horizon.Format("2006-01-02"),
+		"massive:crypto:X:NEWCO@1d":           yesterday,
+		"massive:crypto:X:NEWCO@1d#earliest":  horizon.AddDate(0, 6, 0).Format("2006-01-02"), // its list date
+	}); err != nil {
+		t.Fatal(err)
+	}
+	lt := &fakeLifetimes{lifetimes: map[string]Lifetime{
+		"X:DEAD":  {Delisted: day("2024-03-01")},
+		"X:GONE":  {Delisted: day("2023-12-29")},
+		"X:NEWCO": {Listed: horizon.AddDate(0, 6, 0)},
+	}}
+	l, _ := layout.New(layout.Default)
+	targets := BuildTargets([]string{"X:BTCUSD", "X:DEAD", "X:GONE", "X:NEWCO"}, "data", l, "massive", AssetCrypto, "1d", Adjusted)
+	p := &ProgressProducer{Targets: targets, Progress: store, Lifetimes: lt, APIKey: "k"}
+
+	jobs := produce(p)
+
+	if got := jobs["X:DEAD"]; len(got) != 1 || !got[0].From.Equal(day("2024-02-01")) || !got[0].To.Equal(endOf("2024-03-01")) {
+		t.Errorf("X:DEAD jobs = %v, want one clamped to 2024-02-01..2024-03-01", dates(got))
+	}
+	if len(jobs["X:BTCUSD"]) != 1 || len(jobs) != 2 {
+		t.Errorf("jobs for %v, want X:BTCUSD and X:DEAD only", slices.Sorted(maps.Keys(jobs)))
+	}
+	if !slices.Equal(lt.asked, []string{"X:DEAD", "X:GONE", "X:NEWCO"}) {
+		t.Errorf("looked up %v, want only the long ranges", lt.asked)
+	}
+	m, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m["massive:crypto:X:GONE@1d"]; got != yesterday {
+		t.Errorf("X:GONE last day = %s, want the skipped range recorded up to %s", got, yesterday)
+	}
+	if got := m["massive:crypto:X:NEWCO@1d#earliest"]; got != horizon.Format("2006-01-02") {
+		t.Errorf("X:NEWCO earliest = %s, want the skipped history recorded from %s", got, horizon.Format("2006-01-02"))
+	}
+
+	// The next cycle neither looks the skipped tickers up nor queues them.
+	lt.asked = nil
+	jobs = produce(p)
+	if len(lt.asked) != 1 || lt.asked[0] != "X:DEAD" || len(jobs["X:GONE"]) != 0 || len(jobs["X:NEWCO"]) != 0 {
+		t.Errorf("second cycle looked up %v, queued %d X:GONE and %d X:NEWCO jobs; want only X:DEAD looked up again",
+			lt.asked, len(jobs["X:GONE"]), len(jobs["X:NEWCO"]))
+	}
+}
@@ -2,25 +2,52 @@ package crawl
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"us-data/internal/fsutil"
+	"us-data/internal/layout"
 )
 
 // ProgressUpdate is sent when a crawl unit succeeds.
 // We include Source/Class so that the same symbol (e.g. "BTCUSD") in different
-// markets/providers can be tracked independently in the progress file.
+// markets/providers can be tracked independently in the progress file, and
+// Timeframe so that several bar resolutions can be backfilled side by side.
 type ProgressUpdate struct {
-	Source string
-	Class  AssetClass
-	Ticker string
-	Date   string
+	Source    string
+	Class     AssetClass
+	Ticker    string
+	Timeframe string // e.g. "5min", "1d"; "5min_raw" for unadjusted bars (see Job.seriesTimeframe)
+	Date      string
+	Earliest  bool // Date is the new earliest covered day (backward extension), not the last day
+	Rewritten bool // Date is a split whose history rewrite completed (see rewrittenKey)
+
+	// flush, when non-nil, marks a barrier: RunProgressWriter persists every
+	// update received before it and replies with the write error (nil on success).
+	flush chan error
+}
+
+// FlushProgress blocks until every update sent on updates before this call
+// has been persisted by RunProgressWriter, and returns the store error if the
+// write failed.
+func FlushProgress(updates chan<- ProgressUpdate) error {
+	done := make(chan error, 1)
+	updates <- ProgressUpdate{flush: done}
+	return <-done
 }
 
-func progressKey(source string, class AssetClass, symbol string) string {
+// progressKey identifies one crawled series: source:class:TICKER@timeframe.
+// An empty timeframe yields the pre-timeframe key (source:class:TICKER), which
+// is only read to upgrade old entries (see BootstrapProgress).
+func progressKey(source string, class AssetClass, symbol, timeframe string) string {
 	s := strings.TrimSpace(strings.ToLower(source))
 	if s == "" {
 		s = DefaultSource
@@ -29,78 +56,409 @@ func progressKey(source string, class AssetClass, symbol string) string {
 	if c == "" {
 		c = string(DefaultAssetClass)
 	}
-	return fmt.Sprintf("%s:%s:%s", s, c, strings.ToUpper(strings.TrimSpace(symbol)))
+	key := fmt.Sprintf("%s:%s:%s", s, c, strings.ToUpper(strings.TrimSpace(symbol)))
+	if tf := strings.TrimSpace(timeframe); tf != "" {
+		key += "@" + tf
+	}
+	return key
 }
 
-func loadProgress(path string) map[string]string {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return make(map[string]string)
+// earliestKey returns the key holding the earliest covered day of the series
+// whose last-day entry is key. Coverage is the range [earliest, last].
+func earliestKey(key string) string { return key + "#earliest" }
+
+// advances reports whether next may replace the stored date cur of key
+// (see ProgressStore.Put): #earliest dates only move back, every other date
+// only forward.
+func advances(key, cur, next string) bool {
+	if _, err := time.Parse("2006-01-02", cur); err != nil {
+		return true
 	}
-	var m map[string]string
-	if err := json.Unmarshal(data, &m); err != nil {
-		return make(map[string]string)
+	if strings.HasSuffix(key, earliestKey("")) {
+		return next < cur
 	}
-	return m
+	return next > cur
 }
 
-func saveProgress(path string, m map[string]string) error {
-	data, err := json.MarshalIndent(m, "", "  ")
-	if err != nil {
+// splitKey holds the date of the latest split that invalidated the stored
+// adjusted history of key's series; rewrittenKey the latest split whose
+// rewrite completed. A rewrite is pending while split > rewritten.
+func splitKey(key string) string     { return key + "#split" }
+func rewrittenKey(key string) string { return key + "#rewritten" }
+
+// jobProgressKey returns the progress key of job's series.
+func jobProgressKey(job Job) string {
+	return progressKey(job.Source, job.Class, job.Ticker, job.seriesTimeframe())
+}
+
+// JSONProgressStore is the file-backed ProgressStore (.lastday.json).
+//
+// The whole map is kept in memory and rewritten atomically (temp file +
+// fsync + rename) on every Put, so a crash never leaves a truncated file.
+// Callers batch updates (see RunProgressWriter) to keep the O(n) rewrite rare.
+type JSONProgressStore struct {
+	path string
+	mu   sync.Mutex
+	m    map[string]string // nil until first Load/Put
+}
+
+// NewJSONProgressStore returns a store backed by the JSON file at path.
+func NewJSONProgressStore(path string) *JSONProgressStore {
+	return &JSONProgressStore{path: path}
+}
+
+// Path returns the backing file path.
+func (s *JSONProgressStore) Path() string { return s.path }
+
+// Load returns a copy of all entries. A missing file is an empty store; an
+// unreadable or corrupt file is an error, so callers never mistake it for
+// "no progress" and trigger a full re-backfill.
+func (s *JSONProgressStore) Load() (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+	return maps.Clone(s.m), nil
+}
+
+// Put upserts the entries that advance coverage and rewrites the file
+// atomically.
+func (s *JSONProgressStore) Put(entries map[string]string) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
 		return err
 	}
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+	changed := false
+	for k, v := range entries {
+		if cur, ok := s.m[k]; ok && !advances(k, cur, v) {
+			continue
+		}
+		s.m[k] = v
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return s.write()
+}
+
+// Delete removes keys and rewrites the file atomically.
+func (s *JSONProgressStore) Delete(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	for _, k := range keys {
+		delete(s.m, k)
+	}
+	return s.write()
 }
 
-// BootstrapProgress ensures that progress file has an entry for every Job
-// identity (Source/Class/Ticker). For missing ones it seeds last-day so that
-// the next crawl starts from 2 years ago.
-func BootstrapProgress(path string, targets []Job, now time.Time) {
-	m := loadProgress(path)
-	if m == nil {
-		m = make(map[string]string)
+// write persists the whole map; callers hold s.mu.
+func (s *JSONProgressStore) write() error {
+	data, err := json.MarshalIndent(s.m, "", "  ")
+	if err != nil {
+		return err
 	}
+	return fsutil.WriteFileAtomic(s.path, data, 0o644)
+}
+
+// Close is a no-op; every Put is already durable.
+func (s *JSONProgressStore) Close() error { return nil }
 
-	// Seed so that next crawl starts from (now - 2y)
-	start := time.Date(now.Year()-2, now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-	seedLast := start.AddDate(0, 0, -1).Format("2006-01-02") // last + 1 = start
+func (s *JSONProgressStore) ensureLoaded() error {
+	if s.m != nil {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.m = make(map[string]string)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read progress %s: %w", s.path, err)
+	}
+	m := make(map[string]string)
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("parse progress %s (fix or remove the file): %w", s.path, err)
+		}
+	}
+	s.m = m
+	return nil
+}
 
-	added := 0
+// MigrateProgress copies every entry of src into dst, keeping whichever date
+// is newer. Legacy plain-ticker keys (written before composite keys existed,
+// stocks only) are rewritten to progressKey(source, stocks, ticker).
+// Returns the number of entries written.
+func MigrateProgress(src, dst ProgressStore, source string) (int, error) {
+	in, err := src.Load()
+	if err != nil {
+		return 0, err
+	}
+	cur, err := dst.Load()
+	if err != nil {
+		return 0, err
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		if !strings.Contains(k, ":") {
+			k = progressKey(source, DefaultAssetClass, k, "")
+		}
+		if existing, ok := out[k]; ok && existing >= v {
+			continue
+		}
+		if existing, ok := cur[k]; ok && existing >= v {
+			continue
+		}
+		out[k] = v
+	}
+	if err := dst.Put(out); err != nil {
+		return 0, err
+	}
+	return len(out), nil
+}
+
+// HasProgress reports whether m holds any progress entry of ticker (any
+// timeframe, adjustment or stage).
+func HasProgress(m map[string]string, source string, class AssetClass, ticker string) bool {
+	base := progressKey(source, class, ticker, "")
+	for k := range m {
+		if k == base || strings.HasPrefix(k, base+"@") {
+			return true
+		}
+	}
+	return false
+}
+
+// RenameProgress moves every progress entry of ticker from to ticker to
+// (same source and class) after a symbol change, so the new symbol resumes
+// where the old one stopped instead of starting a fresh backfill. Nothing is
+// moved when to already has progress of its own. It returns the number of
+// entries moved.
+func RenameProgress(store ProgressStore, source string, class AssetClass, from, to string) (int, error) {
+	m, err := store.Load()
+	if err != nil {
+		return 0, err
+	}
+	if HasProgress(m, source, class, to) {
+		return 0, nil
+	}
+	oldBase := progressKey(source, class, from, "")
+	newBase := progressKey(source, class, to, "")
+	moved := make(map[string]string)
+	var stale []string
+	for k, v := range m {
+		if k == oldBase || strings.HasPrefix(k, oldBase+"@") {
+			moved[newBase+strings.TrimPrefix(k, oldBase)] = v
+			stale = append(stale, k)
+		}
+	}
+	if len(moved) == 0 {
+		return 0, nil
+	}
+	if err := store.Put(moved); err != nil {
+		return 0, err
+	}
+	if err := store.Delete(stale); err != nil {
+		// The new keys are in place; stale old ones only cost a later backfill
+		// of the old symbol if it is ever crawled again.
+		slog.Warn("progress rename: old entries not removed", "ticker", from, "err", err)
+	}
+	return len(moved), nil
+}
+
+// BootstrapProgress ensures that the progress store has an entry for every
+// Job identity (Source/Class/Ticker/Timeframe). Missing ones are seeded with
+// an empty coverage at the backfill horizon (earliest = now − backfillYears,
+// last-day = the day before), so the next crawl fetches the full history.
+//
+// Entries written before timeframes were part of the key (source:class:TICKER
+// and legacy plain TICKER) are upgraded to the currently configured timeframe
+// and removed, so switching resolution later starts a fresh backfill instead
+// of inheriting the old series' progress.
+//
+// Series crawled before earliest dates were tracked get the first day of
+// their oldest stored bar file (see oldestStoredDay). Without one the date is
+// an approximation: the fixed 2-year seed used until then, counted from now,
+// so raising backfillYears extends them backward from there.
+func BootstrapProgress(store ProgressStore, targets []Job, now time.Time, backfillYears int) {
+	m, err := store.Load()
+	if err != nil {
+		slog.Error("progress bootstrap skipped: load failed", "err", err)
+		return
+	}
+
+	horizon := backfillHorizon(now, backfillYears)
+	seedFirst := horizon.Format("2006-01-02")
+	seedLast := horizon.AddDate(0, 0, -1).Format("2006-01-02") // last + 1 = horizon
+	legacyFirst := backfillHorizon(now, 2)
+
+	added := make(map[string]string)
+	var stale []string
+	seeded, upgraded := 0, 0
 	for _, target := range targets {
-		key := progressKey(target.Source, target.Class, target.Ticker)
-		if _, ok := m[key]; ok {
+		key := jobProgressKey(target)
+		var old []string
+		if target.adjustment() == Adjusted { // raw series postdate the old key formats
+			if target.Timeframe != "" {
+				old = append(old, progressKey(target.Source, target.Class, target.Ticker, ""))
+			}
+			old = append(old, target.Ticker) // legacy plain ticker entry
+		}
+
+		last, have := m[key]
+		for _, k := range old {
+			v, ok := m[k]
+			if !ok {
+				continue
+			}
+			stale = append(stale, k)
+			if !have && added[key] < v { // an existing timeframe key always wins
+				added[key] = v
+			}
+		}
+		if v, ok := added[key]; ok {
+			upgraded++
+			last, have = v, true
+		}
+		if !have {
+			seeded++
+			added[key] = seedLast
+			added[earliestKey(key)] = seedFirst
 			continue
 		}
-		// Backwards-compat: if legacy plain ticker entry exists, keep it and
-		// don't overwrite; future writes will use the new composite key.
-		if _, okLegacy := m[target.Ticker]; okLegacy {
+		if _, ok := m[earliestKey(key)]; ok {
 			continue
 		}
-		m[key] = seedLast
-		added++
+		first, ok := oldestStoredDay(target)
+		if !ok {
+			first = legacyFirst
+		}
+		if lastDay, err := time.ParseInLocation("2006-01-02", last, time.UTC); err == nil && lastDay.Before(first) {
+			first = lastDay.AddDate(0, 0, 1) // never claim coverage past the last day
+		}
+		added[earliestKey(key)] = first.Format("2006-01-02")
 	}
-	if added == 0 {
+	if len(added) == 0 && len(stale) == 0 {
 		return
 	}
 
-	if err := saveProgress(path, m); err != nil {
+	if err := store.Put(added); err != nil {
 		slog.Warn("progress bootstrap write failed", "err", err)
 		return
 	}
-	slog.Info("progress bootstrapped", "path", path, "new_entries", added)
+	if err := store.Delete(stale); err != nil {
+		slog.Warn("progress bootstrap cleanup failed", "err", err)
+	}
+	slog.Info("progress bootstrapped",
+		"new_entries", seeded, "upgraded", upgraded, "removed_old_keys", len(stale))
 }
 
-// RunProgressWriter receives updates and persists to file (run as goroutine)
-func RunProgressWriter(path string, updates <-chan ProgressUpdate) {
-	m := loadProgress(path)
+// oldestStoredDay returns the first day of the oldest bar file of target's
+// series, read from the file names under its ticker directory
+// ({prefix}YYYY-MM-DD…, or a YYYY-MM / YYYY partition). ok is false when no
+// file of the series is found.
+func oldestStoredDay(target Job) (first time.Time, ok bool) {
+	prefix := layout.FilePrefix(target.Ticker, target.seriesTimeframe())
+	root := filepath.Join(target.SaveDir, target.tickerDir())
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasPrefix(d.Name(), prefix) {
+			return nil
+		}
+		rest := d.Name()[len(prefix):]
+		for _, l := range []string{"2006-01-02", "2006-01", "2006"} {
+			if len(rest) < len(l) {
+				continue
+			}
+			day, err := time.ParseInLocation(l, rest[:len(l)], time.UTC)
+			if err != nil {
+				continue
+			}
+			if !ok || day.Before(first) {
+				first, ok = day, true
+			}
+			break
+		}
+		return nil
+	})
+	return first, ok
+}
+
+// progressBatchMax bounds how many queued updates RunProgressWriter folds
+// into a single store write.
+const progressBatchMax = 512
+
+// RunProgressWriter receives updates and persists them to store (run as goroutine).
+// Updates already queued are folded into one batched Put, so a burst of
+// checkpoints costs one write. Coverage only grows: Put drops a last-day
+// update older than the stored date (e.g. a late chunk checkpoint) and an
+// earliest-day update newer than the stored one, comparing against what is
+// stored at the time, including direct writes made since the writer started.
+//
+// No update is ever dropped: a batch whose Put fails is kept and retried with
+// the next one, and flush barriers (see FlushProgress) report the failure.
+func RunProgressWriter(store ProgressStore, updates <-chan ProgressUpdate) {
+	pending := make(map[string]string) // accepted but not yet persisted
 	for u := range updates {
-		key := progressKey(u.Source, u.Class, u.Ticker)
-		m[key] = u.Date
-		if err := saveProgress(path, m); err != nil {
-			slog.Warn("progress write failed", "ticker", u.Ticker, "err", err)
+		var barriers []chan error
+		add := func(u ProgressUpdate) {
+			if u.flush != nil {
+				barriers = append(barriers, u.flush)
+				return
+			}
+			key := progressKey(u.Source, u.Class, u.Ticker, u.Timeframe)
+			switch {
+			case u.Earliest:
+				key = earliestKey(key)
+			case u.Rewritten:
+				key = rewrittenKey(key)
+			}
+			if cur, ok := pending[key]; ok && !advances(key, cur, u.Date) {
+				return
+			}
+			pending[key] = u.Date
+		}
+		add(u)
+	drain:
+		for len(pending) < progressBatchMax {
+			select {
+			case next, ok := <-updates:
+				if !ok {
+					break drain
+				}
+				add(next)
+			default:
+				break drain
+			}
+		}
+
+		var err error
+		if len(pending) > 0 {
+			if err = store.Put(pending); err != nil {
+				slog.Warn("progress write failed, will retry with next batch",
+					"entries", len(pending), "err", err)
+			} else {
+				pending = make(map[string]string)
+			}
+		}
+		for _, b := range barriers {
+			b <- err
+		}
+	}
+	if len(pending) > 0 {
+		if err := store.Put(pending); err != nil {
+			slog.Error("progress lost on shutdown", "entries", len(pending), "err", err)
 		}
 	}
 }
@@ -0,0 +1,275 @@
+package crawl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"us-data/internal/layout"
+)
+
+func TestJSONStoreRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lastday.json")
+	if err := os.WriteFile(path, []byte(`{"massive:stocks:AAPL": "2025-`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewJSONProgressStore(path)
+	if _, err := s.Load(); err == nil {
+		t.Fatal("expected error for truncated progress file")
+	}
+	if err := s.Put(map[string]string{"massive:stocks:MSFT": "2025-01-01"}); err == nil {
+		t.Fatal("Put must not overwrite a corrupt progress file")
+	}
+}
+
+func TestBootstrapUpgradesKeysToTimeframe(t *testing.T) {
+	db := NewJSONProgressStore(filepath.Join(t.TempDir(), ".lastday.json"))
+	if err := db.Put(map[string]string{
+		"AAPL":                     "2025-01-10", // legacy plain key
+		"massive:stocks:AAPL":      "2025-01-12", // pre-timeframe key, newer
+		"massive:stocks:MSFT":      "2025-01-01",
+		"massive:stocks:MSFT@5min": "2025-03-01", // timeframe key wins
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	l, _ := layout.New(layout.Default)
+	targets := BuildTargets([]string{"AAPL", "MSFT", "NVDA"}, "data", l, "massive", AssetStocks, "5min", Adjusted)
+	// A raw series is new: it never inherits the adjusted series' old keys.
+	targets = append(targets, BuildTargets([]string{"AAPL"}, "data", l, "massive", AssetStocks, "5min", Raw)...)
+	BootstrapProgress(db, targets, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), 2)
+
+	got, err := db.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"massive:stocks:AAPL@5min": "2025-01-12",
+		"massive:stocks:MSFT@5min": "2025-03-01",
+		"massive:stocks:NVDA@5min": "2023-05-31",
+
+		"massive:stocks:AAPL@5min#earliest": "2023-06-01",
+		"massive:stocks:MSFT@5min#earliest": "2023-06-01",
+		"massive:stocks:NVDA@5min#earliest": "2023-06-01",
+
+		"massive:stocks:AAPL@5min_raw":          "2023-05-31",
+		"massive:stocks:AAPL@5min_raw#earliest": "2023-06-01",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestRenameProgressMovesEverySeries(t *testing.T) {
+	db := NewJSONProgressStore(filepath.Join(t.TempDir(), ".lastday.json"))
+	if err := db.Put(map[string]string{
+		"massive:stocks:FB@5min":            "2022-06-08",
+		"massive:stocks:FB@5min#earliest":   "2020-06-01",
+		"massive:stocks:FB@5min_raw":        "2022-06-08",
+		"massive:stocks:FB@actions":         "2022-06-08",
+		"massive:stocks:FBIO@5min":          "2022-06-08", // another ticker sharing the prefix
+		"massive:stocks:MSFT@5min":          "2022-06-08",
+		"massive:stocks:MSFT@5min#earliest": "2020-06-01",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := RenameProgress(db, "massive", AssetStocks, "FB", "META")
+	if err != nil || n != 4 {
+		t.Fatalf("RenameProgress = %d, %v; want 4 entries moved", n, err)
+	}
+	got, err := db.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"massive:stocks:META@5min":          "2022-06-08",
+		"massive:stocks:META@5min#earliest": "2020-06-01",
+		"massive:stocks:META@5min_raw":      "2022-06-08",
+		"massive:stocks:META@actions":       "2022-06-08",
+		"massive:stocks:FBIO@5min":          "2022-06-08",
+		"massive:stocks:MSFT@5min":          "2022-06-08",
+		"massive:stocks:MSFT@5min#earliest": "2020-06-01",
+	}
+	if len(got) != len(want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+
+	// A symbol with progress of its own is never overwritten.
+	if n, err := RenameProgress(db, "massive", AssetStocks, "FBIO", "MSFT"); err != nil || n != 0 {
+		t.Errorf("rename onto existing series = %d, %v; want 0", n, err)
+	}
+}
+
+func TestBootstrapSeedsEarliestFromStoredFiles(t *testing.T) {
+	dir := t.TempDir()
+	db := NewJSONProgressStore(filepath.Join(dir, ".lastday.json"))
+	if err := db.Put(map[string]string{
+		"massive:stocks:AAPL@5min": "2025-01-10", // crawled before earliest dates were tracked
+		"massive:stocks:MSFT@5min": "2025-01-10",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"AAPL/AAPL_5min_2021-03-01_to_2022-02-28.csv",
+		"AAPL/2022/AAPL_5min_2022.csv",
+		"AAPL/AAPL_5min_raw_2019-01-02_to_2019-12-31.csv", // another series
+		"AAPL/AAPL_1d_2018-01-02_to_2018-12-31.csv",       // another timeframe
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, _ := layout.New(layout.Default)
+	targets := BuildTargets([]string{"AAPL", "MSFT"}, dir, l, "massive", AssetStocks, "5min", Adjusted)
+	BootstrapProgress(db, targets, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), 5)
+
+	got, err := db.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := got["massive:stocks:AAPL@5min#earliest"]; v != "2021-03-01" {
+		t.Errorf("AAPL earliest = %q, want the oldest stored file 2021-03-01", v)
+	}
+	if v := got["massive:stocks:MSFT@5min#earliest"]; v != "2023-06-01" {
+		t.Errorf("MSFT earliest = %q, want the 2-year approximation 2023-06-01", v)
+	}
+}
+
+// flakyStore is a JSON store whose first failPuts Puts fail; it counts the
+// Puts attempted.
+type flakyStore struct {
+	*JSONProgressStore
+	failPuts, puts int
+}
+
+func (s *flakyStore) Put(entries map[string]string) error {
+	s.puts++
+	if s.failPuts > 0 {
+		s.failPuts--
+		return errors.New("disk full")
+	}
+	return s.JSONProgressStore.Put(entries)
+}
+
+// startWriter runs RunProgressWriter on store; the returned stop closes the
+// updates channel and waits for the writer to return.
+func startWriter(store ProgressStore, updates chan ProgressUpdate) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		RunProgressWriter(store, updates)
+		close(done)
+	}()
+	return func() {
+		close(updates)
+		<-done
+	}
+}
+
+// update5min is a last-day (or, with earliest, earliest-day) update of the
+// 5min series of ticker.
+func update5min(ticker, date string, earliest bool) ProgressUpdate {
+	return ProgressUpdate{Source: "massive", Class: AssetStocks, Ticker: ticker, Timeframe: "5min", Date: date, Earliest: earliest}
+}
+
+func newFlakyStore(t *testing.T, failPuts int) *flakyStore {
+	return &flakyStore{JSONProgressStore: NewJSONProgressStore(filepath.Join(t.TempDir(), ".lastday.json")), failPuts: failPuts}
+}
+
+func TestProgressWriterBatchesQueuedUpdates(t *testing.T) {
+	store := newFlakyStore(t, 0)
+	updates := make(chan ProgressUpdate, 8)
+	updates <- update5min("AAPL", "2025-06-03", false)
+	updates <- update5min("AAPL", "2025-06-05", false)
+	updates <- update5min("AAPL", "2025-06-04", false) // a late chunk: dropped
+	updates <- update5min("MSFT", "2025-06-04", false)
+	stop := startWriter(store, updates)
+	defer stop()
+
+	if err := FlushProgress(updates); err != nil {
+		t.Fatal(err)
+	}
+	if store.puts != 1 {
+		t.Errorf("%d Puts, want the queued updates in one batch", store.puts)
+	}
+	got, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalEntries(t, got, map[string]string{
+		"massive:stocks:AAPL@5min": "2025-06-05",
+		"massive:stocks:MSFT@5min": "2025-06-04",
+	})
+}
+
+func TestProgressWriterRetriesFailedPut(t *testing.T) {
+	store := newFlakyStore(t, 1)
+	updates := make(chan ProgressUpdate, 8)
+	stop := startWriter(store, updates)
+	defer stop()
+
+	updates <- update5min("AAPL", "2025-06-05", false)
+	if err := FlushProgress(updates); err == nil {
+		t.Fatal("flush succeeded, want the Put error")
+	}
+	updates <- update5min("MSFT", "2025-06-04", false)
+	if err := FlushProgress(updates); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalEntries(t, got, map[string]string{
+		"massive:stocks:AAPL@5min": "2025-06-05", // kept and written with the next batch
+		"massive:stocks:MSFT@5min": "2025-06-04",
+	})
+}
+
+func TestProgressWriterNeverShrinksStoredCoverage(t *testing.T) {
+	store := newFlakyStore(t, 0)
+	updates := make(chan ProgressUpdate, 8)
+	stop := startWriter(store, updates)
+	defer stop()
+
+	// Written directly once the writer runs, as BootstrapProgress and
+	// RenameProgress do.
+	if err := store.Put(map[string]string{
+		"massive:stocks:AAPL@5min":          "2025-06-10",
+		"massive:stocks:AAPL@5min#earliest": "2023-06-01",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	updates <- update5min("AAPL", "2025-06-05", false)
+	updates <- update5min("AAPL", "2024-01-02", true)
+	updates <- update5min("MSFT", "2022-01-03", true)
+	if err := FlushProgress(updates); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalEntries(t, got, map[string]string{
+		"massive:stocks:AAPL@5min":          "2025-06-10",
+		"massive:stocks:AAPL@5min#earliest": "2023-06-01",
+		"massive:stocks:MSFT@5min#earliest": "2022-01-03",
+	})
+}
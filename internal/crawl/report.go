@@ -2,49 +2,167 @@ package crawl
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
+
+	"us-data/internal/fsutil"
 )
 
-type failedEntry struct {
+// reportEntry is one job in the run report.
+type reportEntry struct {
+	Ticker    string `json:"ticker"`
+	Class     string `json:"class,omitempty"`
+	Raw       bool   `json:"raw,omitempty"`     // unadjusted series
+	Rewrite   string `json:"rewrite,omitempty"` // split date of a history rewrite
+	DateRange string `json:"date_range"`
+	Bars      int    `json:"bars,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	ErrorKind string `json:"error_kind,omitempty"` // rate_limited | not_found | network | no_data | …
+	Uncovered string `json:"uncovered,omitempty"`  // range still missing; progress stops before it
+	Attempts  int    `json:"attempts"`
+
+	status ResultStatus // not serialized: selects the report section
+	job    Job          // not serialized: re-enqueued by the retry stage
+}
+
+// runReport is .lastrun.json: one section per ResultStatus.
+type runReport struct {
+	Start    time.Time            `json:"start"`
+	Duration string               `json:"duration"`
+	Counts   map[ResultStatus]int `json:"counts"`
+
+	OK        []reportEntry `json:"ok"`
+	Empty     []reportEntry `json:"empty"`
+	Skipped   []reportEntry `json:"skipped"`
+	Transient []reportEntry `json:"transient_error"`
+	Permanent []reportEntry `json:"permanent_error"`
+
+	// Rewrites repeats the split rewrites of this run, whatever their outcome.
+	Rewrites []reportEntry `json:"split_rewrites,omitempty"`
+
+	Actions *actionsReport `json:"corporate_actions,omitempty"` // nil when the stage did not run
+}
+
+// Legacy per-outcome reports, written before .lastrun.json existed and still
+// written next to it for the scripts that read them. Each holds the jobs of
+// the last run only: a file with nothing to list is removed.
+const (
+	legacySuccessReport = ".lastrun.success.json" // []legacySuccess: ok and empty jobs
+	legacyFailedReport  = ".lastrun.failed.json"  // []legacyFailure: both error statuses
+)
+
+type legacySuccess struct {
+	Ticker    string `json:"ticker"`
+	DateRange string `json:"date_range"`
+	Bars      int    `json:"bars"`
+	Attempts  int    `json:"attempts"`
+}
+
+type legacyFailure struct {
 	Ticker    string `json:"ticker"`
 	DateRange string `json:"date_range"`
 	Reason    string `json:"reason"`
+	Kind      string `json:"kind"`                 // transient | permanent
+	ErrorKind string `json:"error_kind,omitempty"` // rate_limited | not_found | network | …
+	Attempts  int    `json:"attempts"`
 }
 
-func writeRunReport(saveBaseDir string, successList []string, failedList []failedEntry) error {
-	if err := os.MkdirAll(saveBaseDir, 0755); err != nil {
-		return err
+func newRunReport(start time.Time, entries []reportEntry) runReport {
+	rep := runReport{
+		Start:    start,
+		Duration: time.Since(start).Round(time.Second).String(),
+		Counts:   make(map[ResultStatus]int),
 	}
-	if len(successList) > 0 {
-		p := filepath.Join(saveBaseDir, ".lastrun.success.json")
-		data, err := json.MarshalIndent(successList, "", "  ")
-		if err != nil {
-			return err
+	for _, e := range entries {
+		rep.Counts[e.status]++
+		if e.Rewrite != "" {
+			rep.Rewrites = append(rep.Rewrites, e)
 		}
-		if err := os.WriteFile(p, data, 0644); err != nil {
-			return err
+		switch e.status {
+		case StatusOK:
+			rep.OK = append(rep.OK, e)
+		case StatusEmpty:
+			rep.Empty = append(rep.Empty, e)
+		case StatusSkipped:
+			rep.Skipped = append(rep.Skipped, e)
+		case StatusTransient:
+			rep.Transient = append(rep.Transient, e)
+		default:
+			rep.Permanent = append(rep.Permanent, e)
 		}
-		slog.Info("report wrote success", "path", p, "tickers", len(successList))
 	}
-	if len(failedList) > 0 {
-		p := filepath.Join(saveBaseDir, ".lastrun.failed.json")
-		data, err := json.MarshalIndent(failedList, "", "  ")
-		if err != nil {
-			return err
+	return rep
+}
+
+func writeRunReport(saveBaseDir string, rep runReport) error {
+	if err := os.MkdirAll(saveBaseDir, 0755); err != nil {
+		return err
+	}
+	p := filepath.Join(saveBaseDir, ".lastrun.json")
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(p, data, 0644); err != nil {
+		return err
+	}
+	if err := writeLegacyReports(saveBaseDir, rep); err != nil {
+		return err
+	}
+	slog.Info("report written", "path", p,
+		"ok", len(rep.OK), "empty", len(rep.Empty), "skipped", len(rep.Skipped),
+		"transient_error", len(rep.Transient), "permanent_error", len(rep.Permanent))
+	return nil
+}
+
+// writeLegacyReports writes the legacy success and failure files of rep.
+func writeLegacyReports(saveBaseDir string, rep runReport) error {
+	var success []legacySuccess
+	for _, e := range slices.Concat(rep.OK, rep.Empty) {
+		success = append(success, legacySuccess{Ticker: e.Ticker, DateRange: e.DateRange, Bars: e.Bars, Attempts: e.Attempts})
+	}
+	var failed []legacyFailure
+	for _, sec := range []struct {
+		kind    string
+		entries []reportEntry
+	}{{"transient", rep.Transient}, {"permanent", rep.Permanent}} {
+		for _, e := range sec.entries {
+			failed = append(failed, legacyFailure{
+				Ticker: e.Ticker, DateRange: e.DateRange, Reason: e.Reason,
+				Kind: sec.kind, ErrorKind: e.ErrorKind, Attempts: e.Attempts,
+			})
 		}
-		if err := os.WriteFile(p, data, 0644); err != nil {
+	}
+	if err := writeLegacyReport(filepath.Join(saveBaseDir, legacySuccessReport), success, len(success)); err != nil {
+		return err
+	}
+	return writeLegacyReport(filepath.Join(saveBaseDir, legacyFailedReport), failed, len(failed))
+}
+
+// writeLegacyReport writes list (of n entries) to path, or removes path when
+// the list is empty.
+func writeLegacyReport(path string, list any, n int) error {
+	if n == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return err
 		}
-		slog.Info("report wrote failed", "path", p, "count", len(failedList))
+		return nil
 	}
-	return nil
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, data, 0644)
 }
 
-func joinFailedReasons(failedList []failedEntry) string {
+func joinFailedReasons(failedList []reportEntry) string {
 	if len(failedList) == 0 {
 		return ""
 	}
@@ -0,0 +1,54 @@
+package crawl
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteRunReportKeepsLegacyFiles(t *testing.T) {
+	dir := t.TempDir()
+	rep := newRunReport(time.Now(), []reportEntry{
+		{Ticker: "AAPL", DateRange: "2024-06-03..2024-06-07", Bars: 960, Attempts: 1, status: StatusOK},
+		{Ticker: "HALT", DateRange: "2024-06-03..2024-06-07", Attempts: 1, status: StatusEmpty},
+		{Ticker: "MSFT", DateRange: "2024-06-03..2024-06-07", Attempts: 3, Reason: "429", ErrorKind: "rate_limited", status: StatusTransient},
+		{Ticker: "XXXX", DateRange: "2024-06-03..2024-06-07", Attempts: 1, Reason: "404", ErrorKind: "not_found", status: StatusPermanent},
+	})
+	if err := writeRunReport(dir, rep); err != nil {
+		t.Fatal(err)
+	}
+
+	var success []legacySuccess
+	readJSON(t, filepath.Join(dir, legacySuccessReport), &success)
+	if len(success) != 2 || success[0].Ticker != "AAPL" || success[0].Bars != 960 || success[1].Ticker != "HALT" {
+		t.Errorf("success = %+v", success)
+	}
+	var failed []legacyFailure
+	readJSON(t, filepath.Join(dir, legacyFailedReport), &failed)
+	if len(failed) != 2 || failed[0].Kind != "transient" || failed[0].Attempts != 3 || failed[1].Kind != "permanent" || failed[1].Reason != "404" {
+		t.Errorf("failed = %+v", failed)
+	}
+
+	// A clean run removes the stale failure list.
+	if err := writeRunReport(dir, newRunReport(time.Now(), rep.OK)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, legacyFailedReport)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("stale %s left behind: %v", legacyFailedReport, err)
+	}
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
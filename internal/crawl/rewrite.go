@@ -0,0 +1,188 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"us-data/internal/calendar"
+	"us-data/internal/fsutil"
+	"us-data/internal/layout"
+)
+
+// rewriteStageDir is the directory under a class SaveDir where rewrite jobs
+// write the new history of a ticker before it replaces the live one.
+const rewriteStageDir = ".rewrite"
+
+// splitsCheckedKey holds the last day the market-wide split list was checked
+// for source (see detectSplits).
+func splitsCheckedKey(source string) string {
+	s := strings.TrimSpace(strings.ToLower(source))
+	if s == "" {
+		s = DefaultSource
+	}
+	return s + ":splits@checked"
+}
+
+// detectSplits marks the adjusted series whose stored history a split has
+// invalidated. Adjusted bars are adjusted for the splits known when they were
+// fetched, so every bar saved before a split's execution date is off by its
+// ratio from then on.
+//
+// It checks the splits executed since the last check up to yesterday and, for
+// every adjusted target holding bars from before the split, records the split
+// date in the series' #split key. The producer turns a pending split into a
+// rewrite job (see resolveRewriteRange). The first check only starts the
+// clock: history fetched before it is assumed current.
+func (r *Runner) detectSplits(ctx context.Context, ss SplitSource, now time.Time) {
+	m, err := r.Progress.Load()
+	if err != nil {
+		slog.Error("split check skipped: progress load failed", "err", err)
+		return
+	}
+	source := DefaultSource
+	if len(r.Targets) > 0 {
+		source = r.Targets[0].Source
+	}
+	checkedKey := splitsCheckedKey(source)
+	to := date(now).AddDate(0, 0, -1)
+
+	checked, ok := m[checkedKey]
+	if !ok {
+		if err := r.Progress.Put(map[string]string{checkedKey: to.Format("2006-01-02")}); err != nil {
+			slog.Warn("split check: progress write failed", "err", err)
+		}
+		slog.Info("split check started", "from", to.AddDate(0, 0, 1).Format("2006-01-02"))
+		return
+	}
+	last, err := time.ParseInLocation("2006-01-02", checked, time.UTC)
+	if err != nil {
+		slog.Warn("split check: bad checked date, reset", "value", checked)
+		last = to
+	}
+	from := last.AddDate(0, 0, 1)
+	if from.After(to) {
+		return
+	}
+
+	splits, err := ss.Splits(ctx, r.APIKeys[0], from, to)
+	if err != nil {
+		slog.Warn("split check failed, retried next cycle", "err", err) // checked day not moved
+		return
+	}
+
+	byTicker := make(map[string][]Job)
+	for _, t := range r.Targets {
+		if t.adjustment() == Adjusted {
+			byTicker[strings.ToUpper(t.Ticker)] = append(byTicker[strings.ToUpper(t.Ticker)], t)
+		}
+	}
+	entries := map[string]string{checkedKey: to.Format("2006-01-02")}
+	for _, s := range splits {
+		for _, t := range byTicker[strings.ToUpper(s.Ticker)] {
+			key := jobProgressKey(t)
+			first, lastDay := m[earliestKey(key)], m[key]
+			if first == "" || lastDay < first || first >= s.Date {
+				continue // no bars before the split: nothing to rewrite
+			}
+			if cur := m[splitKey(key)]; cur >= s.Date || entries[splitKey(key)] >= s.Date {
+				continue
+			}
+			entries[splitKey(key)] = s.Date
+			slog.Info("split detected, adjusted history will be refetched",
+				"ticker", t.Ticker, "class", t.Class, "timeframe", t.Timeframe,
+				"date", s.Date, "ratio", fmt.Sprintf("%g:%g", s.SplitTo, s.SplitFrom))
+		}
+	}
+	if err := r.Progress.Put(entries); err != nil {
+		slog.Warn("split check: progress write failed", "err", err)
+	}
+	slog.Info("split check done", "from", from.Format("2006-01-02"), "to", to.Format("2006-01-02"),
+		"splits", len(splits), "rewrites", len(entries)-1)
+}
+
+// resolveRewriteRange returns the range a pending split rewrite of target
+// refetches: the whole stored history, from the earliest covered day to
+// yesterday. split is the split date; ok is false when nothing is pending.
+func resolveRewriteRange(target Job, m map[string]string, now time.Time) (from, to time.Time, split string, ok bool) {
+	key := jobProgressKey(target)
+	split = m[splitKey(key)]
+	if split == "" || split <= m[rewrittenKey(key)] {
+		return time.Time{}, time.Time{}, "", false
+	}
+	first, err := time.ParseInLocation("2006-01-02", m[earliestKey(key)], time.UTC)
+	if err != nil {
+		return time.Time{}, time.Time{}, "", false
+	}
+	endOfYesterday := date(now).Add(-time.Millisecond)
+	from, to, ok = calendar.Trim(target.tradingCalendar(), first, endOfYesterday)
+	return from, to, split, ok
+}
+
+// clearRewriteStage removes what an earlier, unfinished rewrite of job's
+// ticker left in the staging directory.
+func clearRewriteStage(job Job) error {
+	return os.RemoveAll(filepath.Join(job.writeDir(), job.tickerDir()))
+}
+
+// commitRewrite makes the refetched history of a rewrite job live.
+//
+// The staging directory holds the new bar files of the series. Every other
+// file of the live ticker directory (other timeframes, corporate actions),
+// at any depth, is hard-linked into it first, then both directories are
+// exchanged in one atomic rename: a reader listing the ticker directory sees
+// either the old or the new history, never a mix or nothing. The old
+// directory is removed last.
+//
+// Bar files of the series are recognised by their name prefix
+// {ticker}_{timeframe}_ (see BarFetcher.SaveBars and Job.seriesTimeframe). Cleanup problems after
+// the swap are logged to logs; they do not undo the commit.
+func commitRewrite(job Job, logs chan<- LogEntry) error {
+	live := filepath.Join(job.SaveDir, job.tickerDir())
+	staged := filepath.Join(job.writeDir(), job.tickerDir())
+
+	if _, err := os.Stat(live); errors.Is(err, fs.ErrNotExist) {
+		return os.Rename(staged, live) // nothing live to replace
+	}
+	if err := os.MkdirAll(staged, 0o755); err != nil {
+		return err
+	}
+	series := layout.FilePrefix(job.Ticker, job.seriesTimeframe())
+	err := filepath.WalkDir(live, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), series) {
+			return nil // series files are replaced by the staged ones
+		}
+		rel, err := filepath.Rel(live, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(staged, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.Link(path, target); err != nil && !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("keep %s: %w", rel, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := fsutil.ExchangeDirs(staged, live); err != nil {
+		return fmt.Errorf("swap %s: %w", live, err)
+	}
+	// staged now holds the old directory.
+	if err := os.RemoveAll(staged); err != nil {
+		logs <- LogEntry{slog.LevelWarn, "rewrite: old history not removed", []any{"dir", staged, "err", err}}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package crawl
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"us-data/internal/layout"
+	"us-data/internal/model"
+)
+
+// writeFiles creates the files (relative path → content) under root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readFiles returns every file under root (relative path → content).
+func readFiles(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		files[filepath.ToSlash(rel)] = string(b)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// equalEntries reports every entry of want that got lacks or holds differently.
+func equalEntries(t *testing.T, got, want map[string]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+// rewriteJob returns the adjusted 5min AAPL rewrite job saved under dir.
+func rewriteJob(dir string) Job {
+	l, _ := layout.New(layout.Default)
+	job := BuildTargets([]string{"AAPL"}, dir, l, "massive", AssetStocks, "5min", Adjusted)[0]
+	job.Rewrite = "2025-05-30"
+	return job
+}
+
+// liveTicker is a ticker directory holding the 5min series, another
+// timeframe, a nested partition and the corporate actions.
+var liveTicker = map[string]string{
+	"AAPL/AAPL_5min_2024-01-02_to_2024-06-28.csv": "old 5min",
+	"AAPL/AAPL_1d_2024-01-02_to_2024-06-28.csv":   "1d",
+	"AAPL/year=2024/AAPL_1h_2024.csv":             "1h",
+	"AAPL/AAPL_actions.csv":                       "actions",
+}
+
+func TestCommitRewriteKeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	job := rewriteJob(dir)
+	writeFiles(t, dir, liveTicker)
+	writeFiles(t, filepath.Join(dir, rewriteStageDir), map[string]string{
+		"AAPL/AAPL_5min_2024-01-02_to_2025-05-29.csv": "new 5min",
+	})
+
+	if err := commitRewrite(job, make(chan LogEntry, 4)); err != nil {
+		t.Fatal(err)
+	}
+	equalEntries(t, readFiles(t, filepath.Join(dir, "AAPL")), map[string]string{
+		"AAPL_5min_2024-01-02_to_2025-05-29.csv": "new 5min",
+		"AAPL_1d_2024-01-02_to_2024-06-28.csv":   "1d",
+		"year=2024/AAPL_1h_2024.csv":             "1h",
+		"AAPL_actions.csv":                       "actions",
+	})
+	if _, err := os.Stat(filepath.Join(dir, rewriteStageDir, "AAPL")); !os.IsNotExist(err) {
+		t.Errorf("old history left in the stage: %v", err)
+	}
+}
+
+func TestRewriteRerunClearsStaleStage(t *testing.T) {
+	dir := t.TempDir()
+	job := rewriteJob(dir)
+	writeFiles(t, dir, liveTicker)
+	// An interrupted earlier attempt left part of its history behind.
+	stage := filepath.Join(dir, rewriteStageDir)
+	writeFiles(t, stage, map[string]string{"AAPL/AAPL_5min_2019-01-02_to_2019-06-28.csv": "stale"})
+
+	if err := clearRewriteStage(job); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, stage, map[string]string{"AAPL/AAPL_5min_2024-01-02_to_2025-05-29.csv": "new 5min"})
+	if err := commitRewrite(job, make(chan LogEntry, 4)); err != nil {
+		t.Fatal(err)
+	}
+	got := readFiles(t, filepath.Join(dir, "AAPL"))
+	if _, ok := got["AAPL_5min_2019-01-02_to_2019-06-28.csv"]; ok {
+		t.Error("stale staged file made live")
+	}
+	if got["AAPL_5min_2024-01-02_to_2025-05-29.csv"] != "new 5min" || got["AAPL_actions.csv"] != "actions" {
+		t.Errorf("live = %v", got)
+	}
+}
+
+func TestRewriteRetryAfterFailedCommit(t *testing.T) {
+	dir := t.TempDir()
+	job := rewriteJob(dir)
+	writeFiles(t, dir, liveTicker)
+	stage := filepath.Join(dir, rewriteStageDir)
+	// A file where the stage needs the year=2024 directory of a kept file.
+	writeFiles(t, stage, map[string]string{
+		"AAPL/AAPL_5min_2024-01-02_to_2025-05-29.csv": "new 5min",
+		"AAPL/year=2024": "in the way",
+	})
+
+	if err := commitRewrite(job, make(chan LogEntry, 4)); err == nil {
+		t.Fatal("commit succeeded, want an error")
+	}
+	equalEntries(t, readFiles(t, filepath.Join(dir, "AAPL")), map[string]string{
+		"AAPL_5min_2024-01-02_to_2024-06-28.csv": "old 5min",
+		"AAPL_1d_2024-01-02_to_2024-06-28.csv":   "1d",
+		"year=2024/AAPL_1h_2024.csv":             "1h",
+		"AAPL_actions.csv":                       "actions",
+	})
+
+	// The retry starts from an empty stage, as every attempt does.
+	if err := clearRewriteStage(job); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, stage, map[string]string{"AAPL/AAPL_5min_2024-01-02_to_2025-05-29.csv": "new 5min"})
+	if err := commitRewrite(job, make(chan LogEntry, 4)); err != nil {
+		t.Fatal(err)
+	}
+	equalEntries(t, readFiles(t, filepath.Join(dir, "AAPL")), map[string]string{
+		"AAPL_5min_2024-01-02_to_2025-05-29.csv": "new 5min",
+		"AAPL_1d_2024-01-02_to_2024-06-28.csv":   "1d",
+		"year=2024/AAPL_1h_2024.csv":             "1h",
+		"AAPL_actions.csv":                       "actions",
+	})
+}
+
+// fakeSplits is a SplitSource serving canned splits and recording the
+// ranges asked for.
+type fakeSplits struct {
+	splits []model.CorporateAction
+	asked  []string
+}
+
+func (f *fakeSplits) Splits(_ context.Context, _ string, from, to time.Time) ([]model.CorporateAction, error) {
+	f.asked = append(f.asked, from.Format("2006-01-02")+".."+to.Format("2006-01-02"))
+	return f.splits, nil
+}
+
+func TestDetectSplitsFirstCheckOnlyRecordsDate(t *testing.T) {
+	store := NewJSONProgressStore(filepath.Join(t.TempDir(), ".lastday.json"))
+	if err := store.Put(map[string]string{
+		"massive:stocks:AAPL@5min":          "2025-05-30",
+		"massive:stocks:AAPL@5min#earliest": "2023-06-01",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ss := &fakeSplits{splits: []model.CorporateAction{{Ticker: "AAPL", Type: model.ActionSplit, Date: "2025-05-30", SplitFrom: 1, SplitTo: 4}}}
+	r := &Runner{Progress: store, Targets: []Job{rewriteJob("data")}, APIKeys: []string{"k"}}
+
+	r.detectSplits(context.Background(), ss, time.Date(2025, 6, 2, 6, 0, 0, 0, time.UTC))
+
+	if len(ss.asked) != 0 {
+		t.Errorf("splits fetched for %v on the first check", ss.asked)
+	}
+	got, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalEntries(t, got, map[string]string{
+		"massive:stocks:AAPL@5min":          "2025-05-30",
+		"massive:stocks:AAPL@5min#earliest": "2023-06-01",
+		"massive:splits@checked":            "2025-06-01",
+	})
+}
+
+func TestDetectSplitsAndResolveRewriteRange(t *testing.T) {
+	store := NewJSONProgressStore(filepath.Join(t.TempDir(), ".lastday.json"))
+	if err := store.Put(map[string]string{
+		"massive:splits@checked":                "2025-05-20",
+		"massive:stocks:AAPL@5min":              "2025-05-30",
+		"massive:stocks:AAPL@5min#earliest":     "2023-06-01",
+		"massive:stocks:MSFT@5min":              "2025-05-30",
+		"massive:stocks:MSFT@5min#earliest":     "2025-05-28", // listed after its split
+		"massive:stocks:NVDA@5min_raw":          "2025-05-30",
+		"massive:stocks:NVDA@5min_raw#earliest": "2023-06-01",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	l, _ := layout.New(layout.Default)
+	targets := BuildTargets([]string{"AAPL", "MSFT"}, "data", l, "massive", AssetStocks, "5min", Adjusted)
+	targets = append(targets, BuildTargets([]string{"NVDA"}, "data/raw", l, "massive", AssetStocks, "5min", Raw)...)
+	ss := &fakeSplits{splits: []model.CorporateAction{
+		{Ticker: "AAPL", Type: model.ActionSplit, Date: "2025-05-23", SplitFrom: 1, SplitTo: 4},
+		{Ticker: "MSFT", Type: model.ActionSplit, Date: "2025-05-23", SplitFrom: 1, SplitTo: 2},
+		{Ticker: "NVDA", Type: model.ActionSplit, Date: "2025-05-23", SplitFrom: 1, SplitTo: 10}, // raw bars stay valid
+	}}
+	r := &Runner{Progress: store, Targets: targets, APIKeys: []string{"k"}}
+	now := time.Date(2025, 6, 2, 6, 0, 0, 0, time.UTC)
+
+	r.detectSplits(context.Background(), ss, now)
+
+	if len(ss.asked) != 1 || ss.asked[0] != "2025-05-21..2025-06-01" {
+		t.Errorf("splits fetched for %v, want 2025-05-21..2025-06-01", ss.asked)
+	}
+	m, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["massive:splits@checked"] != "2025-06-01" || m["massive:stocks:AAPL@5min#split"] != "2025-05-23" {
+		t.Errorf("progress = %v, want checked 2025-06-01 and an AAPL split", m)
+	}
+	for _, k := range []string{"massive:stocks:MSFT@5min#split", "massive:stocks:NVDA@5min_raw#split"} {
+		if v, ok := m[k]; ok {
+			t.Errorf("%s = %q, want no split", k, v)
+		}
+	}
+
+	// The pending rewrite refetches the whole stored history up to yesterday.
+	from, to, split, ok := resolveRewriteRange(targets[0], m, now)
+	if !ok || split != "2025-05-23" || from.Format("2006-01-02") != "2023-06-01" || to.Format("2006-01-02") != "2025-05-30" {
+		t.Errorf("rewrite range = %v..%v split %q ok %v, want 2023-06-01..2025-05-30 split 2025-05-23", from, to, split, ok)
+	}
+	if _, _, _, ok := resolveRewriteRange(targets[1], m, now); ok {
+		t.Error("MSFT has a rewrite pending, want none")
+	}
+	m["massive:stocks:AAPL@5min#rewritten"] = "2025-05-23"
+	if _, _, _, ok := resolveRewriteRange(targets[0], m, now); ok {
+		t.Error("completed rewrite still pending")
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"log/slog"
 	"sync"
 	"time"
+
+	"us-data/internal/ctxutil"
+	"us-data/internal/model"
 )
 
 // Runner orchestrates one full crawl cycle.
@@ -21,10 +24,42 @@ type Runner struct {
 	Fetcher         BarFetcher
 	APIKeys         []string
 	Targets         []Job
-	ProgressPath    string
+	Progress        ProgressStore
 	SaveBaseDir     string
 	ProgressUpdates chan<- ProgressUpdate
 	BackfillYears   int // passed to ProgressProducer; 0 → default 2
+	ChunkDays       int // passed to ProgressProducer; >0 splits jobs into chunk-level sub-jobs
+
+	// In-cycle retry of transient failures (network errors, 429, 5xx).
+	// MaxAttempts counts the first try; <= 1 disables retries. Pass n waits
+	// RetryBaseDelay × 2^(n-2) before re-enqueueing, capped at maxRetryBackoff.
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+
+	// Empty decides when a range that returned no bars is complete.
+	Empty EmptyPolicy
+
+	// ActionClasses enables the corporate actions stage (see runActions) for
+	// these asset classes when Fetcher implements ActionFetcher.
+	ActionClasses []AssetClass
+
+	// RefetchOnSplit checks for new splits every cycle when Fetcher
+	// implements SplitSource and rewrites the adjusted history they
+	// invalidated (see detectSplits).
+	RefetchOnSplit bool
+
+	// Partition splits saved bars into one file per trading day, month or
+	// year; "" or PartitionNone saves one file per saved range. The Runner
+	// splits and passes the partition to every SaveBars; the fetcher keeps
+	// no partition of its own.
+	Partition Partition
+
+	// MinFreeBytes pauses every write while the filesystem of SaveBaseDir
+	// has less free space; 0 disables the check.
+	MinFreeBytes uint64
+
+	assembler *chunkAssembler
+	disk      *diskGuard
 }
 
 // Run starts one crawl cycle asynchronously and returns a channel that receives
@@ -42,18 +77,69 @@ func (r *Runner) execute(ctx context.Context, done chan<- Done) {
 	slog.Info("cycle start", "targets", len(r.Targets), "workers", len(r.APIKeys))
 
 	// Bootstrap must run before producer reads progress, so every target has an entry.
-	BootstrapProgress(r.ProgressPath, r.Targets, start)
+	BootstrapProgress(r.Progress, r.Targets, start, r.BackfillYears)
+	if ss, ok := r.Fetcher.(SplitSource); ok && r.RefetchOnSplit && len(r.APIKeys) > 0 {
+		r.detectSplits(ctx, ss, start)
+	}
 
-	producer := NewProgressProducer(r.Targets, r.ProgressPath, r.BackfillYears)
+	r.assembler = newChunkAssembler()
+	r.disk = &diskGuard{dir: r.SaveBaseDir, min: r.MinFreeBytes}
+	producer := NewProgressProducer(r.Targets, r.Progress, r.BackfillYears, r.ChunkDays)
+	if ls, ok := r.Fetcher.(LifetimeSource); ok && len(r.APIKeys) > 0 {
+		producer.Lifetimes, producer.APIKey = ls, r.APIKeys[0]
+	}
 	jobCh := producer.Start(ctx)
-	successes, failures := r.runWorkers(ctx, jobCh)
 
+	var final []reportEntry
+	for attempt := 1; ; attempt++ {
+		// Transient failures go back into the queue until MaxAttempts;
+		// everything else (including permanent failures) is final.
+		var retry []reportEntry
+		for _, e := range r.runWorkers(ctx, jobCh, attempt) {
+			if e.status == StatusTransient && attempt < r.MaxAttempts && ctx.Err() == nil {
+				retry = append(retry, e)
+				continue
+			}
+			final = append(final, e)
+		}
+		if len(retry) == 0 {
+			break
+		}
+
+		delay := retryBackoff(r.RetryBaseDelay, attempt)
+		slog.Info("retrying transient failures",
+			"jobs", len(retry), "attempt", attempt+1, "of", r.MaxAttempts, "backoff", delay)
+		if err := ctxutil.Sleep(ctx, delay); err != nil {
+			final = append(final, retry...)
+			break
+		}
+		jobs := make([]Job, len(retry))
+		for i, e := range retry {
+			jobs[i] = e.job
+		}
+		jobCh = r.retryQueue(jobs, attempt+1)
+	}
+
+	var actions *actionsReport
+	if af, ok := r.Fetcher.(ActionFetcher); ok && len(r.ActionClasses) > 0 && ctx.Err() == nil {
+		actions = r.runActions(ctx, af, start)
+	}
+
+	// Every checkpoint of this cycle must be durable before Done is signalled.
+	if err := FlushProgress(r.ProgressUpdates); err != nil {
+		slog.Error("progress flush failed", "err", err)
+	}
+
+	report := newRunReport(start, final)
+	report.Actions = actions
 	slog.Info("cycle done",
-		"success", len(successes), "failed", len(failures),
-		"duration", time.Since(start).Round(time.Second))
+		"ok", report.Counts[StatusOK], "empty", report.Counts[StatusEmpty],
+		"skipped", report.Counts[StatusSkipped],
+		"failed", report.Counts[StatusTransient]+report.Counts[StatusPermanent],
+		"duration", report.Duration)
 
-	if len(successes) > 0 || len(failures) > 0 {
-		if err := writeRunReport(r.SaveBaseDir, successes, failures); err != nil {
+	if len(final) > 0 || actions != nil {
+		if err := writeRunReport(r.SaveBaseDir, report); err != nil {
 			slog.Warn("run report write failed", "err", err)
 		}
 	}
@@ -61,7 +147,7 @@ func (r *Runner) execute(ctx context.Context, done chan<- Done) {
 
 // runWorkers fans jobs out to N workers (one per API key) and collects results.
 // Workers communicate exclusively through channels — no direct slog calls.
-func (r *Runner) runWorkers(ctx context.Context, jobCh <-chan Job) (successes []string, failures []failedEntry) {
+func (r *Runner) runWorkers(ctx context.Context, jobCh <-chan Job, pass int) (entries []reportEntry) {
 	keyPool := make(chan string, len(r.APIKeys))
 	for _, k := range r.APIKeys {
 		keyPool <- k
@@ -94,16 +180,19 @@ func (r *Runner) runWorkers(ctx context.Context, jobCh <-chan Job) (successes []
 		defer resWg.Done()
 		for res := range results {
 			mu.Lock()
-			if res.Ok {
+			entries = append(entries, reportEntry{
+				Ticker: res.Ticker, Class: string(res.Job.Class), Raw: res.Job.adjustment() == Raw, Rewrite: res.Job.Rewrite,
+				DateRange: res.DateRange,
+				Bars:      res.Bars, Reason: res.Reason, ErrorKind: res.ErrorKind, Uncovered: res.Uncovered,
+				Attempts: res.Attempts,
+				status:   res.Status, job: res.Job,
+			})
+			if res.Status.Failed() {
+				failedCount++
+			} else {
 				successCount++
-				successes = appendUniq(successes, res.Ticker)
 				barsPerTicker[res.Ticker] += res.Bars
 				barsPerKey[res.KeyPrefix] += res.Bars
-			} else {
-				failedCount++
-				failures = append(failures, failedEntry{
-					Ticker: res.Ticker, DateRange: res.DateRange, Reason: res.Reason,
-				})
 			}
 			mu.Unlock()
 		}
@@ -128,85 +217,354 @@ func (r *Runner) runWorkers(ctx context.Context, jobCh <-chan Job) (successes []
 					if !open {
 						return
 					}
-					r.processJob(job, keyPool, results, logs)
+					r.processJob(ctx, job, keyPool, results, logs)
 				}
 			}
 		}()
 	}
 
 	wg.Wait()
+	// A cancelled pass leaves chunk groups whose remaining chunks were never
+	// fetched. Their flushed prefix is checkpointed; report the rest as
+	// uncovered, as processJob does for a cancelled job.
+	if r.assembler != nil && ctx.Err() != nil {
+		for _, g := range r.assembler.abandon() {
+			job, resume := g.parent, g.parent.remaining(g.covered)
+			dateRange := job.From.Format("2006-01-02") + ".." + job.To.Format("2006-01-02")
+			logs <- LogEntry{slog.LevelWarn, "fetch cancelled", []any{
+				"ticker", job.Ticker, "class", job.Class, "range", dateRange, "bars_checkpointed", g.total,
+			}}
+			results <- JobResult{
+				Status: StatusTransient, Ticker: job.Ticker,
+				DateRange: dateRange, Reason: "cancelled: " + ctx.Err().Error(),
+				Attempts: job.attempt(), Job: resume, ErrorKind: "cancelled",
+				Uncovered: resume.From.Format("2006-01-02") + ".." + resume.To.Format("2006-01-02"),
+			}
+		}
+	}
 	close(results)
 	close(logs)
 	resWg.Wait()
 	logWg.Wait()
 
-	logSummary(barsPerTicker, barsPerKey, failures, successCount, failedCount)
-	return
+	logSummary(pass, barsPerTicker, barsPerKey, entries)
+	return entries
+}
+
+// retryQueue feeds jobs for the given attempt number into a closed channel,
+// re-splitting them into chunk-level sub-jobs when chunking is enabled.
+func (r *Runner) retryQueue(jobs []Job, attempt int) <-chan Job {
+	var subs []Job
+	for _, j := range jobs {
+		j.Attempt = attempt
+		subs = append(subs, splitJob(j, r.ChunkDays)...)
+	}
+	out := make(chan Job, len(subs))
+	for _, j := range subs {
+		out <- j
+	}
+	close(out)
+	return out
 }
 
 // processJob fetches and saves bars for a fully-resolved Job.
 // All output goes through channels — results to resultCh, logs to logs.
 // This goroutine never calls slog directly.
-func (r *Runner) processJob(job Job, keyPool chan string, results chan<- JobResult, logs chan<- LogEntry) {
+//
+// ctx is passed down to FetchBars so a shutdown interrupts cooldowns and
+// retries instead of waiting for the whole job to finish.
+func (r *Runner) processJob(ctx context.Context, job Job, keyPool chan string, results chan<- JobResult, logs chan<- LogEntry) {
 	fromStr := job.From.Format("2006-01-02")
 	toStr := job.To.Format("2006-01-02")
 
-	key := <-keyPool
+	var key string
+	select {
+	case key = <-keyPool:
+	case <-ctx.Done():
+		return
+	}
 	keyPfx := key
 	if len(key) > 8 {
 		keyPfx = key[:8] + "…"
 	}
 	defer func() { keyPool <- key }()
 
-	logs <- LogEntry{slog.LevelInfo, "fetch start", []any{
-		"ticker", job.Ticker, "class", job.Class,
-		"from", fromStr, "to", toStr, "key", keyPfx,
-	}}
+	if job.isChunk() {
+		logs <- LogEntry{slog.LevelDebug, "fetch chunk start", []any{
+			"ticker", job.Ticker, "class", job.Class, "part", job.Part + 1, "of", job.Parts,
+			"from", fromStr, "to", toStr, "key", keyPfx,
+		}}
+	} else {
+		logs <- LogEntry{slog.LevelInfo, "fetch start", []any{
+			"ticker", job.Ticker, "class", job.Class,
+			"from", fromStr, "to", toStr, "key", keyPfx,
+		}}
+	}
+
+	// Every completed chunk with data is saved as its own file (named after the
+	// chunk range, so a file never claims more coverage than it holds) and
+	// progress advances to its end. A crash mid-backfill therefore resumes
+	// from the last checkpoint instead of starting over. Empty chunks are not
+	// checkpointed on their own; the next chunk with data covers them.
+	//
+	// Jobs whose progress only moves once the whole range is fetched (an
+	// unchunked backward job, a rewrite) instead stream every chunk into one
+	// file for the job range (see streamChunk): only one chunk is held in
+	// memory, and a failed job aborts the file rather than leaving partial
+	// files that its retry would duplicate.
+	//
+	// With a Partition, bars are saved into the file of their period instead
+	// (see save); a streamed job then saves its chunks without progress, and
+	// its retry merges over whatever a failed attempt saved.
+	//
+	// A rewrite job writes into its staging directory and moves no progress
+	// until commitRewrite has made the whole new history live; every attempt
+	// starts from an empty stage.
+	if job.Rewrite != "" {
+		if err := clearRewriteStage(job); err != nil {
+			logs <- LogEntry{slog.LevelWarn, "rewrite: stale stage not cleared", []any{"ticker", job.Ticker, "err", err}}
+		}
+	}
+	checkGaps := func(part Job, bars []model.Bar) {
+		if missing, expected := sessionGaps(part.tradingCalendar(), part.From, part.To,
+			timeframeInterval(part.Timeframe), bars); len(missing) > 0 {
+			logs <- LogEntry{slog.LevelWarn, "trading days without bars", []any{
+				"ticker", part.Ticker, "class", part.Class, "days", len(missing),
+				"first", missing[0].Format("2006-01-02"), "bars", len(bars), "expected_bars", expected,
+			}}
+		}
+	}
+	// save writes the bars of part into dir, one file per Partition period.
+	// A chunk that cannot be saved fails the job at that chunk, as a fetch
+	// error would: progress never moves past data that is not on disk.
+	save := func(dir string, part Job, bars []model.Bar) error {
+		if err := r.disk.wait(ctx, logs); err != nil {
+			return err
+		}
+		for _, br := range r.Partition.split(part.tradingCalendar(), part, bars) {
+			if err := r.Fetcher.SaveBars(dir, part.Ticker, part.adjustment(), r.Partition, br.from, br.to, br.bars); err != nil {
+				return &saveError{err}
+			}
+		}
+		return nil
+	}
+	checkpoint := func(part Job, bars []model.Bar) error {
+		checkGaps(part, bars)
+		if len(bars) == 0 {
+			return nil
+		}
+		if err := save(part.SaveDir, part, bars); err != nil {
+			return err
+		}
+		r.sendProgress(part)
+		return nil
+	}
+	var stream BarWriter // opened on the first chunk with bars
+	streamChunk := func(part Job, bars []model.Bar) error {
+		checkGaps(part, bars)
+		if len(bars) == 0 {
+			return nil
+		}
+		if r.Partition.partitioned() {
+			return save(job.writeDir(), part, bars)
+		}
+		if err := r.disk.wait(ctx, logs); err != nil {
+			return err
+		}
+		if stream == nil {
+			w, err := r.Fetcher.OpenBars(job.writeDir(), job.Ticker, job.adjustment(), job.From, job.To)
+			if err != nil {
+				return &saveError{err}
+			}
+			stream = w
+		}
+		if err := stream.Append(bars); err != nil {
+			return &saveError{err}
+		}
+		return nil
+	}
+
+	var (
+		total   int
+		covered time.Time // end of the completed prefix, for resuming a retry
+		err     error
+	)
+	if job.isChunk() {
+		// Chunk-level sub-job: the assembler flushes the contiguous prefix in
+		// range order. Only the worker that completes the parent range reports
+		// the result, with the job widened back to the parent range.
+		var bars []model.Bar
+		bars, err = r.Fetcher.FetchBars(ctx, job.Ticker, key, job.adjustment(), job.tradingCalendar(), job.From, job.To, nil)
+		var done bool
+		total, covered, err, done = r.assembler.add(job, bars, err, checkpoint)
+		if !done {
+			return
+		}
+		job = job.parent()
+		fromStr = job.From.Format("2006-01-02")
+		toStr = job.To.Format("2006-01-02")
+	} else {
+		// The provider delivers chunks oldest first, so a backward job's
+		// chunks are not contiguous with its earliest date until the last one
+		// arrives: they are streamed as they come, progress moves at the end.
+		streamed := job.Backward || job.Rewrite != ""
+		_, err = r.Fetcher.FetchBars(ctx, job.Ticker, key, job.adjustment(), job.tradingCalendar(), job.From, job.To,
+			func(from, to time.Time, bars []model.Bar) error {
+				part := job
+				part.From, part.To = from, to
+				if streamed {
+					if err := streamChunk(part, bars); err != nil {
+						return err
+					}
+				} else {
+					if err := checkpoint(part, bars); err != nil {
+						return err
+					}
+					covered = to
+				}
+				total += len(bars)
+				return nil
+			})
+		if stream != nil {
+			if err == nil {
+				if cerr := stream.Commit(); cerr != nil {
+					err = &saveError{cerr}
+				}
+			} else if aerr := stream.Abort(); aerr != nil {
+				logs <- LogEntry{slog.LevelWarn, "partial file not removed", []any{"ticker", job.Ticker, "err", aerr}}
+			}
+		}
+		if streamed && err != nil {
+			total = 0 // the job's progress did not move
+		}
+	}
 
-	bars, err := r.Fetcher.FetchBars(job.Ticker, key, job.From, job.To)
+	// On failure only the contiguous prefix up to covered has been
+	// checkpointed; the rest of the range is the hole a retry must fill.
+	resume := job.remaining(covered)
+	uncovered := resume.From.Format("2006-01-02") + ".." + resume.To.Format("2006-01-02")
 
 	switch {
-	case err != nil:
-		logs <- LogEntry{slog.LevelError, "fetch error", []any{
+	case err != nil && ctx.Err() != nil:
+		logs <- LogEntry{slog.LevelWarn, "fetch cancelled", []any{
 			"ticker", job.Ticker, "class", job.Class,
-			"from", fromStr, "to", toStr, "key", keyPfx, "err", err,
+			"from", fromStr, "to", toStr, "key", keyPfx, "bars_checkpointed", total,
 		}}
 		results <- JobResult{
-			Ok: false, Ticker: job.Ticker,
+			Status: StatusTransient, Ticker: job.Ticker,
+			DateRange: fromStr + ".." + toStr, Reason: "cancelled: " + ctx.Err().Error(),
+			Attempts: job.attempt(), Job: resume, Uncovered: uncovered, ErrorKind: "cancelled",
+		}
+
+	case err != nil:
+		args := []any{
+			"ticker", job.Ticker, "class", job.Class,
+			"from", fromStr, "to", toStr, "key", keyPfx, "err", err, "kind", errorKind(err),
+			"bars_checkpointed", total, "uncovered", uncovered,
+		}
+		if hf, ht, ok := uncoveredRange(err); ok {
+			args = append(args, "provider_hole", hf.Format("2006-01-02")+".."+ht.Format("2006-01-02"))
+		}
+		logs <- LogEntry{slog.LevelError, "fetch error", args}
+		status := StatusPermanent
+		if isTransient(err) {
+			status = StatusTransient
+		}
+		results <- JobResult{
+			Status: status, Ticker: job.Ticker,
 			DateRange: fromStr + ".." + toStr, Reason: err.Error(),
+			Attempts: job.attempt(), Job: resume, Uncovered: uncovered, ErrorKind: errorKind(err),
+		}
+
+	case total == 0 && !job.hasSession():
+		// Only closed days left (e.g. a retry resumed onto a weekend): nothing
+		// to fetch, so the range is complete.
+		logs <- LogEntry{slog.LevelDebug, "no trading session in range", []any{
+			"ticker", job.Ticker, "class", job.Class, "from", fromStr, "to", toStr,
+		}}
+		results <- JobResult{
+			Status: StatusSkipped, Ticker: job.Ticker,
+			DateRange: fromStr + ".." + toStr, Reason: "no trading session", KeyPrefix: keyPfx,
+			Attempts: job.attempt(), Job: job,
+		}
+		r.sendProgress(job)
+
+	case total == 0 && r.Empty.complete(job):
+		// Thinly traded or halted: nothing to fetch, but the range is done.
+		logs <- LogEntry{slog.LevelInfo, "fetch empty, range complete", []any{
+			"ticker", job.Ticker, "class", job.Class,
+			"from", fromStr, "to", toStr,
+		}}
+		results <- JobResult{
+			Status: StatusEmpty, Ticker: job.Ticker,
+			DateRange: fromStr + ".." + toStr, KeyPrefix: keyPfx,
+			Attempts: job.attempt(), Job: job,
 		}
+		r.sendProgress(job)
 
-	case len(bars) == 0:
+	case total == 0:
 		logs <- LogEntry{slog.LevelWarn, "fetch empty", []any{
 			"ticker", job.Ticker, "class", job.Class,
 			"from", fromStr, "to", toStr,
 		}}
 		results <- JobResult{
-			Ok: false, Ticker: job.Ticker,
-			DateRange: fromStr + ".." + toStr, Reason: "no data",
+			Status: StatusPermanent, Ticker: job.Ticker,
+			DateRange: fromStr + ".." + toStr, Reason: "no data", ErrorKind: "no_data",
+			Attempts: job.attempt(), Job: job,
+		}
+
+	case job.Rewrite != "":
+		if err := commitRewrite(job, logs); err != nil {
+			logs <- LogEntry{slog.LevelError, "rewrite commit failed", []any{
+				"ticker", job.Ticker, "class", job.Class, "split", job.Rewrite, "err", err,
+			}}
+			results <- JobResult{
+				Status: StatusTransient, Ticker: job.Ticker,
+				DateRange: fromStr + ".." + toStr, Reason: "rewrite commit: " + err.Error(), ErrorKind: "rewrite",
+				Attempts: job.attempt(), Job: job, Uncovered: fromStr + ".." + toStr,
+			}
+			return
+		}
+		logs <- LogEntry{slog.LevelInfo, "history rewritten", []any{
+			"ticker", job.Ticker, "class", job.Class, "split", job.Rewrite,
+			"from", fromStr, "to", toStr, "bars", total, "key", keyPfx,
+		}}
+		results <- JobResult{
+			Status: StatusOK, Ticker: job.Ticker,
+			DateRange: fromStr + ".." + toStr, Bars: total, KeyPrefix: keyPfx,
+			Attempts: job.attempt(), Job: job,
+		}
+		r.sendProgress(job)
+		r.ProgressUpdates <- ProgressUpdate{
+			Source: job.Source, Class: job.Class, Ticker: job.Ticker,
+			Timeframe: job.seriesTimeframe(), Date: job.Rewrite, Rewritten: true,
 		}
 
 	default:
-		r.Fetcher.SaveBars(job.SaveDir, job.Ticker, job.From, job.To, bars)
 		logs <- LogEntry{slog.LevelInfo, "fetch ok", []any{
 			"ticker", job.Ticker, "class", job.Class,
-			"from", fromStr, "to", toStr, "bars", len(bars), "key", keyPfx,
+			"from", fromStr, "to", toStr, "bars", total, "key", keyPfx,
 		}}
 		results <- JobResult{
-			Ok: true, Ticker: job.Ticker,
-			DateRange: fromStr + ".." + toStr, Bars: len(bars), KeyPrefix: keyPfx,
+			Status: StatusOK, Ticker: job.Ticker,
+			DateRange: fromStr + ".." + toStr, Bars: total, KeyPrefix: keyPfx,
+			Attempts: job.attempt(), Job: job,
 		}
+		// Trailing empty chunks were not checkpointed; the job as a whole is done.
+		r.sendProgress(job)
+	}
+}
 
-		select {
-		case r.ProgressUpdates <- ProgressUpdate{
-			Source: job.Source, Class: job.Class,
-			Ticker: job.Ticker, Date: toStr,
-		}:
-		default:
-			logs <- LogEntry{slog.LevelWarn, "progress update dropped", []any{
-				"ticker", job.Ticker,
-			}}
-		}
+// sendProgress records that job's range is covered: the last day moves to
+// job.To, or for a backward job the earliest day moves to job.From.
+//
+// The send blocks when the writer is behind (backpressure) rather than
+// dropping the update: a lost update means data on disk that the next cycle
+// re-fetches and writes again as a duplicate file.
+func (r *Runner) sendProgress(job Job) {
+	r.ProgressUpdates <- ProgressUpdate{
+		Source: job.Source, Class: job.Class, Ticker: job.Ticker,
+		Timeframe: job.seriesTimeframe(), Date: job.edge().Format("2006-01-02"), Earliest: job.Backward,
 	}
 }
 
@@ -214,13 +572,22 @@ func (r *Runner) processJob(job Job, keyPool chan string, results chan<- JobResu
 // Internal helpers
 // ---------------------------------------------------------------------------
 
-func logSummary(barsPerTicker, barsPerKey map[string]int, failures []failedEntry, success, failed int) {
+func logSummary(pass int, barsPerTicker, barsPerKey map[string]int, entries []reportEntry) {
 	var totalBars int
 	for _, n := range barsPerTicker {
 		totalBars += n
 	}
-	slog.Info("cycle summary",
-		"total_bars", totalBars, "tickers_ok", success, "tickers_failed", failed)
+	counts := make(map[ResultStatus]int)
+	var failures []reportEntry
+	for _, e := range entries {
+		counts[e.status]++
+		if e.status.Failed() {
+			failures = append(failures, e)
+		}
+	}
+	slog.Info("cycle summary", "pass", pass, "total_bars", totalBars,
+		"tickers_ok", counts[StatusOK], "tickers_empty", counts[StatusEmpty],
+		"tickers_skipped", counts[StatusSkipped], "tickers_failed", len(failures))
 	for _, t := range sortedKeys(barsPerTicker) {
 		slog.Debug("ticker bars", "ticker", t, "bars", barsPerTicker[t])
 	}
@@ -233,13 +600,19 @@ func logSummary(barsPerTicker, barsPerKey map[string]int, failures []failedEntry
 	}
 }
 
-func appendUniq(list []string, ticker string) []string {
-	for _, t := range list {
-		if t == ticker {
-			return list
-		}
+// maxRetryBackoff caps the exponential delay between retry passes.
+const maxRetryBackoff = 10 * time.Minute
+
+// retryBackoff returns base × 2^(attempt-1), capped at maxRetryBackoff.
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
 	}
-	return append(list, ticker)
+	return min(d, maxRetryBackoff)
 }
 
 func runHeartbeat(ctx context.Context, interval time.Duration, mu *sync.Mutex, success, failed *int, barsPerTicker map[string]int) {
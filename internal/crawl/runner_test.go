@@ -0,0 +1,343 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"us-data/internal/calendar"
+	"us-data/internal/layout"
+	"us-data/internal/model"
+)
+
+// fakeBars is a BarFetcher that serves two bars per requested day, one
+// onChunk call per day, and fails SaveBars once for each day in saveErr. When
+// fetchErr is set, FetchBars call n (1-based) fails with fetchErr(n) if
+// that is not nil. From the day delayedFrom on, nothing is delivered and
+// FetchBars reports the rest of the range as DELAYED.
+type fakeBars struct {
+	mu          sync.Mutex
+	fetchErr    func(n int) error
+	delayedFrom string
+	fetches     int              // FetchBars calls
+	saveErr     map[string]error // by first day of the saved range
+	saved       []string         // first day of every range saved
+	delivered   int              // onChunk calls
+}
+
+func (f *fakeBars) FetchBars(_ context.Context, _, _ string, _ Adjustment, _ calendar.Calendar, from, to time.Time, onChunk ChunkFunc) ([]model.Bar, error) {
+	f.mu.Lock()
+	f.fetches++
+	n := f.fetches
+	f.mu.Unlock()
+	if f.fetchErr != nil {
+		if err := f.fetchErr(n); err != nil {
+			return nil, err
+		}
+	}
+	if onChunk == nil {
+		return bars(2), nil
+	}
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if d.Format("2006-01-02") == f.delayedFrom {
+			return nil, &delayed{from: d, to: to}
+		}
+		f.mu.Lock()
+		f.delivered++
+		f.mu.Unlock()
+		if err := onChunk(d, d.Add(24*time.Hour-time.Millisecond), bars(2)); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeBars) SaveBars(_, _ string, _ Adjustment, _ Partition, from, _ time.Time, _ []model.Bar) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	day := from.Format("2006-01-02")
+	if err := f.saveErr[day]; err != nil {
+		delete(f.saveErr, day)
+		return err
+	}
+	f.saved = append(f.saved, day)
+	return nil
+}
+
+func (f *fakeBars) OpenBars(string, string, Adjustment, time.Time, time.Time) (BarWriter, error) {
+	return nil, errors.New("not streamed")
+}
+
+// delayed is a DELAYED provider response: [from, to] was not delivered.
+type delayed struct{ from, to time.Time }
+
+func (e *delayed) Error() string                          { return "data delayed" }
+func (e *delayed) Retryable() bool                        { return true }
+func (e *delayed) ErrorKind() string                      { return "delayed" }
+func (e *delayed) UncoveredRange() (time.Time, time.Time) { return e.from, e.to }
+
+// processJobs runs jobs through r.processJob one after the other and returns
+// the results and the progress days sent.
+func processJobs(r *Runner, jobs ...Job) ([]JobResult, []string) {
+	updates := make(chan ProgressUpdate, 16)
+	r.ProgressUpdates = updates
+	keyPool := make(chan string, 1)
+	keyPool <- "key"
+	results := make(chan JobResult, len(jobs))
+	logs := make(chan LogEntry, 256)
+	for _, j := range jobs {
+		r.processJob(context.Background(), j, keyPool, results, logs)
+	}
+	close(results)
+	close(updates)
+
+	var res []JobResult
+	for jr := range results {
+		res = append(res, jr)
+	}
+	var days []string
+	for u := range updates {
+		days = append(days, u.Date)
+	}
+	return res, days
+}
+
+// btcJob returns the X:BTCUSD 5min job over [from, to], saved in a temp dir.
+func btcJob(t *testing.T, from, to string) Job {
+	l, _ := layout.New(layout.Default)
+	job := BuildTargets([]string{"X:BTCUSD"}, t.TempDir(), l, "massive", AssetCrypto, "5min", Adjusted)[0]
+	job.From, job.To = day(from), endOf(to)
+	return job
+}
+
+func TestSaveErrorFailsJob(t *testing.T) {
+	diskFull := errors.New("no space left on device")
+	tests := []struct {
+		name          string
+		failDay       string
+		wantDelivered int
+		wantProgress  []string
+		wantUncovered string
+	}{
+		{"first chunk", "2024-06-01", 1, nil, "2024-06-01..2024-06-03"},
+		{"second chunk", "2024-06-02", 2, []string{"2024-06-01"}, "2024-06-02..2024-06-03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeBars{saveErr: map[string]error{tt.failDay: diskFull}}
+			res, progress := processJobs(&Runner{Fetcher: f}, btcJob(t, "2024-06-01", "2024-06-03"))
+
+			if len(res) != 1 || res[0].Status != StatusTransient || res[0].ErrorKind != "save_error" || res[0].Reason != "save: "+diskFull.Error() {
+				t.Fatalf("results = %+v, want one transient save_error", res)
+			}
+			if res[0].Uncovered != tt.wantUncovered {
+				t.Errorf("uncovered = %s, want %s", res[0].Uncovered, tt.wantUncovered)
+			}
+			// No progress for the chunk that was not saved, nor past it.
+			if !slices.Equal(progress, tt.wantProgress) {
+				t.Errorf("progress = %v, want %v", progress, tt.wantProgress)
+			}
+			if f.delivered != tt.wantDelivered {
+				t.Errorf("chunks delivered = %d, want the fetch to stop at the failed one (%d)", f.delivered, tt.wantDelivered)
+			}
+		})
+	}
+}
+
+func TestChunkAssemblerStopsAtFailedFlush(t *testing.T) {
+	f := &fakeBars{saveErr: map[string]error{"2024-06-02": errors.New("disk full")}}
+	r := &Runner{Fetcher: f, assembler: newChunkAssembler()}
+	parts := chunks(btcJob(t, "2024-06-01", "2024-06-03"), "2024-06-01", 3)
+
+	// Chunk 2 arrives before chunk 1 and is buffered; chunk 1 fails to save.
+	res, progress := processJobs(r, parts[0], parts[2], parts[1])
+
+	if len(res) != 1 || res[0].Status != StatusTransient || res[0].ErrorKind != "save_error" {
+		t.Fatalf("results = %+v, want one transient save_error for the parent", res)
+	}
+	if res[0].DateRange != "2024-06-01..2024-06-03" || res[0].Uncovered != "2024-06-02..2024-06-03" {
+		t.Errorf("range %s, uncovered %s; want the parent range, uncovered from the failed chunk", res[0].DateRange, res[0].Uncovered)
+	}
+	if !slices.Equal(f.saved, []string{"2024-06-01"}) || !slices.Equal(progress, []string{"2024-06-01"}) {
+		t.Errorf("saved %v, progress %v; want only the chunk before the failed flush", f.saved, progress)
+	}
+}
+
+func TestDiskGuardPausesAndResumes(t *testing.T) {
+	const minFree = 100 << 20
+	var calls int
+	free := []uint64{10 << 20, 50 << 20, 200 << 20} // low, low, recovered
+	g := &diskGuard{dir: "data", min: minFree, interval: time.Millisecond, free: func(string) (uint64, error) {
+		n := free[min(calls, len(free)-1)]
+		calls++
+		return n, nil
+	}}
+	logs := make(chan LogEntry, 8)
+
+	if err := g.wait(context.Background(), logs); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("free space checked %d times, want 3", calls)
+	}
+	if err := g.wait(context.Background(), logs); err != nil { // space stays free: no new episode
+		t.Fatal(err)
+	}
+	close(logs)
+	var got []slog.Level
+	for e := range logs {
+		got = append(got, e.Level)
+	}
+	if !slices.Equal(got, []slog.Level{slog.LevelWarn, slog.LevelInfo}) {
+		t.Errorf("logs = %v, want one pause warning and one resume", got)
+	}
+
+	// A shutdown ends the pause.
+	ctx, cancel := context.WithCancel(context.Background())
+	low := &diskGuard{dir: "data", min: minFree, interval: time.Millisecond, free: func(string) (uint64, error) {
+		cancel()
+		return 0, nil
+	}}
+	if err := low.wait(ctx, make(chan LogEntry, 1)); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+// runCycle runs one cycle of r against a JSON store seeded with progress and
+// returns the run report. It fails the test if the cycle does not end.
+func runCycle(t *testing.T, ctx context.Context, r *Runner, progress map[string]string) runReport {
+	t.Helper()
+	r.SaveBaseDir = t.TempDir()
+	r.Progress = NewJSONProgressStore(filepath.Join(r.SaveBaseDir, ".lastday.json"))
+	if err := r.Progress.Put(progress); err != nil {
+		t.Fatal(err)
+	}
+	updates := make(chan ProgressUpdate, 16)
+	r.ProgressUpdates = updates
+	writerDone := make(chan struct{})
+	go func() {
+		RunProgressWriter(r.Progress, updates)
+		close(writerDone)
+	}()
+
+	select {
+	case <-r.Run(ctx):
+	case <-time.After(10 * time.Second):
+		t.Fatal("cycle did not end")
+	}
+	close(updates)
+	<-writerDone
+
+	var rep runReport
+	readJSON(t, filepath.Join(r.SaveBaseDir, ".lastrun.json"), &rep)
+	return rep
+}
+
+// btcProgress is the progress of the X:BTCUSD 5min series, covered from
+// 2024-05-01 to last.
+func btcProgress(last string) map[string]string {
+	return map[string]string{
+		"massive:crypto:X:BTCUSD@5min":          last,
+		"massive:crypto:X:BTCUSD@5min#earliest": "2024-05-01",
+	}
+}
+
+func TestCancelledCycleReportsUncoveredChunks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The second chunk fetch cancels the cycle; every later one sees it.
+	f := &fakeBars{fetchErr: func(n int) error {
+		if n == 2 {
+			cancel()
+		}
+		return ctx.Err()
+	}}
+	target := btcJob(t, "2024-06-01", "2024-06-01")
+	target.From, target.To = time.Time{}, time.Time{}
+	r := &Runner{Fetcher: f, APIKeys: []string{"k"}, Targets: []Job{target}, ChunkDays: 1}
+
+	rep := runCycle(t, ctx, r, btcProgress("2024-05-31"))
+
+	if len(rep.Transient) != 1 || len(rep.OK) != 0 {
+		t.Fatalf("report = %+v, want one transient entry", rep)
+	}
+	e := rep.Transient[0]
+	if e.ErrorKind != "cancelled" || !strings.HasPrefix(e.DateRange, "2024-06-01..") || !strings.HasPrefix(e.Uncovered, "2024-06-02..") {
+		t.Errorf("entry = %+v, want cancelled, uncovered from the second chunk", e)
+	}
+	m, err := r.Progress.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m["massive:crypto:X:BTCUSD@5min"]; got != "2024-06-01" {
+		t.Errorf("last day = %s, want the flushed first chunk 2024-06-01", got)
+	}
+}
+
+func TestRetryResumesFromCheckpoint(t *testing.T) {
+	// The first attempt checkpoints 06-01 and 06-02, then fails to save 06-03.
+	f := &fakeBars{saveErr: map[string]error{"2024-06-03": errors.New("disk full")}}
+	target := btcJob(t, "2024-06-01", "2024-06-01")
+	target.From, target.To = time.Time{}, time.Time{}
+	r := &Runner{Fetcher: f, APIKeys: []string{"k"}, Targets: []Job{target}, MaxAttempts: 2}
+
+	rep := runCycle(t, context.Background(), r, btcProgress("2024-05-31"))
+
+	if len(rep.OK) != 1 || rep.OK[0].Attempts != 2 || !strings.HasPrefix(rep.OK[0].DateRange, "2024-06-03..") {
+		t.Fatalf("report = %+v, want the retry to cover the range from 2024-06-03", rep)
+	}
+	if f.fetches != 2 || !slices.Equal(f.saved[:4], []string{"2024-06-01", "2024-06-02", "2024-06-03", "2024-06-04"}) {
+		t.Errorf("fetches %d, saved %v…; want each checkpointed day saved once", f.fetches, f.saved[:4])
+	}
+	if len(slices.Compact(slices.Clone(f.saved))) != len(f.saved) {
+		t.Error("a checkpointed day was fetched and saved again")
+	}
+}
+
+// unavailable is a retryable provider error.
+type unavailable struct{}
+
+func (unavailable) Error() string   { return "503 service unavailable" }
+func (unavailable) Retryable() bool { return true }
+
+func TestTransientFailureRetriedUpToMaxAttempts(t *testing.T) {
+	f := &fakeBars{fetchErr: func(int) error { return unavailable{} }}
+	target := btcJob(t, "2024-06-01", "2024-06-01")
+	target.From, target.To = time.Time{}, time.Time{}
+	const delay = 20 * time.Millisecond
+	r := &Runner{Fetcher: f, APIKeys: []string{"k"}, Targets: []Job{target}, MaxAttempts: 3, RetryBaseDelay: delay}
+
+	start := time.Now()
+	rep := runCycle(t, context.Background(), r, btcProgress("2024-05-31"))
+
+	if f.fetches != 3 {
+		t.Errorf("fetched %d times, want 3 attempts", f.fetches)
+	}
+	if elapsed := time.Since(start); elapsed < 3*delay { // waits of delay, then 2×delay
+		t.Errorf("cycle took %v, want at least the %v of backoff", elapsed, 3*delay)
+	}
+	if len(rep.Transient) != 1 || rep.Transient[0].Attempts != 3 || rep.Transient[0].Reason != (unavailable{}).Error() {
+		t.Errorf("report = %+v, want one transient failure after 3 attempts", rep)
+	}
+}
+
+func TestDelayedTailReportedUncovered(t *testing.T) {
+	f := &fakeBars{delayedFrom: "2024-06-04"}
+	res, progress := processJobs(&Runner{Fetcher: f}, btcJob(t, "2024-06-01", "2024-06-05"))
+
+	if len(res) != 1 || res[0].Status != StatusTransient || res[0].ErrorKind != "delayed" {
+		t.Fatalf("results = %+v, want one transient delayed failure", res)
+	}
+	if res[0].Uncovered != "2024-06-04..2024-06-05" || res[0].Job.From != day("2024-06-04") {
+		t.Errorf("uncovered %s, retry from %v; want the delayed tail 2024-06-04..2024-06-05", res[0].Uncovered, res[0].Job.From)
+	}
+	if !slices.Equal(progress, []string{"2024-06-01", "2024-06-02", "2024-06-03"}) {
+		t.Errorf("progress = %v, want only the delivered prefix", progress)
+	}
+}
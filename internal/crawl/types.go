@@ -2,9 +2,12 @@ package crawl
 
 import (
 	"log/slog"
+	"path/filepath"
 	"time"
-)
 
+	"us-data/internal/calendar"
+	"us-data/internal/layout"
+)
 
 // AssetClass identifies the type of financial instrument.
 type AssetClass string
@@ -19,26 +22,260 @@ const (
 	DefaultAssetClass = AssetStocks
 )
 
+// Adjustment selects split-adjusted bars (the provider default) or raw,
+// unadjusted ones. The two are separate series: own directory, file names
+// and progress keys.
+type Adjustment string
+
+const (
+	Adjusted Adjustment = "adjusted"
+	Raw      Adjustment = "raw"
+)
+
 // Job is a fully-resolved crawl unit. From/To are computed by the producer
 // (via resolveJobRange) so the worker is a pure "fetch + save" unit with no
 // date or progress logic.
 type Job struct {
-	Source  string
-	Class   AssetClass
-	Ticker  string
-	From    time.Time
-	To      time.Time
-	SaveDir string // e.g. data/Polygon/stocks | data/Polygon/crypto
+	Source    string
+	Class     AssetClass
+	Ticker    string
+	Timeframe string // bar resolution label, e.g. "5min"; part of the progress identity
+	From      time.Time
+	To        time.Time
+	SaveDir   string // e.g. data/Polygon/stocks | data/Polygon/crypto | data/Polygon/raw/stocks
+	TickerDir string // the ticker's directory under SaveDir, e.g. AAPL, X%3ABTCUSD, ticker=AAPL; "" = Ticker
+
+	// ActionsDir is the class directory the ticker's corporate actions are
+	// saved under (see ActionFetcher.SaveActions); "" saves them in SaveDir,
+	// next to the bars.
+	ActionsDir string
+
+	Adjustment Adjustment // "" = Adjusted; Raw series get their own progress key
+
+	// Rewrite, when set, is the date of a split that invalidated the stored
+	// adjusted history: the job refetches the whole covered range into a
+	// staging directory that replaces the ticker directory on success (see
+	// commitRewrite). Rewrite jobs are never split into chunks.
+	Rewrite string
+
+	// Backward marks a history extension job: it fills the range before the
+	// earliest covered date and moves that date down, instead of moving the
+	// last-day forward. Its chunks are flushed newest first.
+	Backward bool
+
+	// Chunk-level sub-jobs (see ProgressProducer.ChunkDays). Parts <= 1 means
+	// the Job covers its whole range and is saved on its own; otherwise it is
+	// chunk Part (0-based) of Parts and SpanFrom/SpanTo is the parent range.
+	Part     int
+	Parts    int
+	SpanFrom time.Time
+	SpanTo   time.Time
+
+	Attempt int // 1-based attempt number within the cycle; 0 = first attempt
+}
+
+// adjustment returns the bar adjustment of j, defaulting to Adjusted.
+func (j Job) adjustment() Adjustment {
+	if j.Adjustment == "" {
+		return Adjusted
+	}
+	return j.Adjustment
+}
+
+// seriesTimeframe returns the timeframe part of j's progress key, spelled as
+// in its file names (see layout.Series): "5min", or "5min_raw" for unadjusted
+// bars.
+func (j Job) seriesTimeframe() string {
+	return layout.Series(j.Timeframe, j.adjustment() == Raw)
+}
+
+// writeDir returns the directory bars of j are saved under: SaveDir, or its
+// staging directory for a rewrite job.
+func (j Job) writeDir() string {
+	if j.Rewrite != "" {
+		return filepath.Join(j.SaveDir, rewriteStageDir)
+	}
+	return j.SaveDir
+}
+
+// actionsDir returns the class directory of j's corporate actions.
+func (j Job) actionsDir() string {
+	if j.ActionsDir == "" {
+		return j.SaveDir
+	}
+	return j.ActionsDir
+}
+
+// tickerDir returns the name of j's ticker directory under SaveDir.
+func (j Job) tickerDir() string {
+	if j.TickerDir == "" {
+		return j.Ticker
+	}
+	return j.TickerDir
+}
+
+// attempt returns the 1-based attempt number.
+func (j Job) attempt() int { return max(j.Attempt, 1) }
+
+// edge returns the date progress moves to once j is covered: To for forward
+// jobs, From (the new earliest date) for backward ones.
+func (j Job) edge() time.Time {
+	if j.Backward {
+		return j.From
+	}
+	return j.To
+}
+
+// remaining returns the part of j not yet fetched and checkpointed, given
+// covered, the edge of the checkpointed part (see edge). Forward jobs resume
+// the day after it; backward jobs end the day before it. Used to retry only
+// the part of a job that is still missing.
+func (j Job) remaining(covered time.Time) Job {
+	if covered.IsZero() {
+		return j
+	}
+	if j.Backward {
+		prev := date(covered).Add(-time.Millisecond) // end of the previous day
+		if prev.Before(j.From) {
+			return j
+		}
+		j.To = prev
+		return j
+	}
+	next := date(covered).AddDate(0, 0, 1)
+	if next.After(j.To) {
+		return j
+	}
+	j.From = next
+	return j
+}
+
+// tradingCalendar returns the exchange calendar of j's asset class.
+func (j Job) tradingCalendar() calendar.Calendar {
+	return calendar.ForClass(string(j.Class))
+}
+
+// hasSession reports whether [From, To] contains at least one trading day.
+func (j Job) hasSession() bool {
+	_, _, ok := calendar.Trim(j.tradingCalendar(), j.From, j.To)
+	return ok
+}
+
+// isChunk reports whether job is one chunk of a split parent range.
+func (j Job) isChunk() bool { return j.Parts > 1 }
+
+// parent returns the whole-range Job a chunk belongs to.
+func (j Job) parent() Job {
+	p := j
+	p.From, p.To = j.SpanFrom, j.SpanTo
+	p.Part, p.Parts = 0, 0
+	p.SpanFrom, p.SpanTo = time.Time{}, time.Time{}
+	return p
+}
+
+// Lifetime is the span a ticker trades: Listed is its first trading day and
+// Delisted its last. A zero bound is unknown (or, for Delisted, still listed).
+type Lifetime struct {
+	Listed   time.Time
+	Delisted time.Time
+}
+
+// clamp narrows [from, to] to the lifetime. ok is false when the range lies
+// entirely outside it.
+func (l Lifetime) clamp(from, to time.Time) (time.Time, time.Time, bool) {
+	if !l.Listed.IsZero() && from.Before(date(l.Listed)) {
+		from = date(l.Listed)
+	}
+	if !l.Delisted.IsZero() {
+		if end := date(l.Delisted).Add(24*time.Hour - time.Millisecond); to.After(end) {
+			to = end
+		}
+	}
+	return from, to, !from.After(to)
+}
+
+// ResultStatus classifies the outcome of a Job. Its value names the section
+// of the run report the job is listed in.
+type ResultStatus string
+
+const (
+	StatusOK        ResultStatus = "ok"              // bars fetched and saved
+	StatusEmpty     ResultStatus = "empty"           // no bars, range accepted as complete (see EmptyPolicy)
+	StatusSkipped   ResultStatus = "skipped"         // nothing to fetch: no trading session in range
+	StatusTransient ResultStatus = "transient_error" // may succeed on retry (network, 429, 5xx, cancelled)
+	StatusPermanent ResultStatus = "permanent_error" // will not succeed as-is (404, 403, unexpected no data)
+)
+
+// Failed reports whether the status is an error.
+func (s ResultStatus) Failed() bool {
+	return s == StatusTransient || s == StatusPermanent
 }
 
 // JobResult is the outcome of one Job, fanned-in to the result collector.
+// Every status except the errors advances progress to the end of the job.
 type JobResult struct {
-	Ok        bool
+	Status    ResultStatus
 	Ticker    string
 	DateRange string
 	Reason    string
 	Bars      int
 	KeyPrefix string
+
+	Attempts  int    // attempts this job has taken so far in the cycle
+	ErrorKind string // provider error kind, e.g. rate_limited, not_found (see errorKind)
+	Uncovered string // failures: the range not yet checkpointed ("from..to"), i.e. Job's range
+	Job       Job    // the job to re-enqueue on retry, starting after the last checkpoint
+}
+
+// EmptyMode selects how EmptyPolicy treats a range that returned no bars.
+type EmptyMode string
+
+const (
+	EmptyAuto     EmptyMode = "auto"     // complete when short, failure when long (default)
+	EmptyComplete EmptyMode = "complete" // always complete
+	EmptyFail     EmptyMode = "fail"     // always a permanent "no data" failure
+)
+
+// DefaultEmptyMaxTradingDays is the EmptyAuto threshold used when
+// EmptyPolicy.MaxTradingDays is not set.
+const DefaultEmptyMaxTradingDays = 5
+
+// EmptyPolicy decides when a fetched range with no bars counts as complete.
+//
+// Thinly traded and halted names legitimately have days without a single
+// bar; refetching those ranges every cycle never yields anything. A long
+// empty range (e.g. a whole backfill) more likely means a wrong or delisted
+// symbol, so EmptyAuto only accepts ranges of at most MaxTradingDays
+// trading days and reports longer ones as failures.
+type EmptyPolicy struct {
+	Mode           EmptyMode
+	MaxTradingDays int
+}
+
+// complete reports whether an empty result for job counts as fetched.
+// A backward extension that finds nothing has reached the start of the
+// ticker's history, which is always complete.
+//
+// A rewrite that finds nothing never is: it would replace the stored history
+// with nothing.
+func (p EmptyPolicy) complete(job Job) bool {
+	if job.Rewrite != "" {
+		return false
+	}
+	if job.Backward {
+		return true
+	}
+	switch p.Mode {
+	case EmptyComplete:
+		return true
+	case EmptyFail:
+		return false
+	}
+	limit := p.MaxTradingDays
+	if limit <= 0 {
+		limit = DefaultEmptyMaxTradingDays
+	}
+	return len(calendar.TradingDays(job.tradingCalendar(), job.From, job.To)) <= limit
 }
 
 // Done signals that a Runner cycle has finished.
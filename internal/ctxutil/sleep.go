@@ -0,0 +1,24 @@
+// Package ctxutil holds small context helpers shared by the crawl engine and
+// the providers.
+package ctxutil
+
+import (
+	"context"
+	"time"
+)
+
+// Sleep pauses for d or until ctx is cancelled, whichever comes first. It
+// returns ctx.Err() when cancelled, also for d <= 0.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
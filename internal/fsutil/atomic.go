@@ -0,0 +1,122 @@
+// Package fsutil holds small filesystem helpers shared by the persistence layers.
+package fsutil
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempInfix marks the temp files of atomic writes: .{name}.tmp-{random},
+// next to the file they become.
+const tempInfix = ".tmp-"
+
+// AtomicFile is a file written under a temp name in its final directory and
+// renamed into place on Commit, so readers of path observe either the old
+// content or the complete new content, never a truncated file. Exactly one of
+// Commit and Abort must be called.
+type AtomicFile struct {
+	*os.File
+	path string
+	perm os.FileMode
+}
+
+// CreateAtomic starts an atomic write of path. The parent directory is
+// created if missing.
+func CreateAtomic(path string, perm os.FileMode) (*AtomicFile, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+tempInfix+"*")
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{File: f, path: path, perm: perm}, nil
+}
+
+// Commit fsyncs the temp file and renames it over path (rename is atomic
+// within one filesystem). On error the temp file is removed and path is
+// left as it was.
+func (f *AtomicFile) Commit() error {
+	err := f.Sync()
+	if err == nil {
+		err = f.Chmod(f.perm)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), f.path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	syncDir(filepath.Dir(f.path))
+	return nil
+}
+
+// Abort closes and removes the temp file; path is left as it was.
+func (f *AtomicFile) Abort() error {
+	_ = f.Close()
+	if err := os.Remove(f.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// WriteFileAtomic writes data to path as one AtomicFile.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := CreateAtomic(path, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return errors.Join(err, f.Abort())
+	}
+	return f.Commit()
+}
+
+// RemoveTemp deletes the temp files of atomic writes under root that a crash
+// left behind, and returns how many it removed. Call it only while nothing
+// writes under root. A missing root holds nothing.
+func RemoveTemp(root string) (int, error) {
+	n := 0
+	var errs []error
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			errs = append(errs, err)
+			return nil
+		}
+		if d.IsDir() || !isTemp(d.Name()) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		n++
+		return nil
+	})
+	return n, errors.Join(append(errs, err)...)
+}
+
+// isTemp reports whether name is that of an AtomicFile's temp file.
+func isTemp(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, tempInfix)
+}
+
+// syncDir fsyncs a directory so a preceding rename survives a power loss.
+// Best effort: some platforms do not support syncing directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+}
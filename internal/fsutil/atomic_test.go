@@ -0,0 +1,77 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicFileCommitAndAbort(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "f.csv")
+
+	f, err := CreateAtomic(path, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("new"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file visible before commit (%v)", err)
+	}
+	if err := f.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != "new" {
+		t.Errorf("after commit: %q, %v", got, err)
+	}
+
+	f, err = CreateAtomic(path, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("partial")
+	if err := f.Abort(); err != nil {
+		t.Fatalf("Abort: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "new" {
+		t.Errorf("after abort: %q, want the committed content", got)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("%d files in dir, want only the committed one", len(entries))
+	}
+}
+
+func TestRemoveTemp(t *testing.T) {
+	dir := t.TempDir()
+	keep := []string{"AAPL_1min_2024-01-02.parquet", ".lastday.json", filepath.Join("AAPL", ".hidden")}
+	orphans := []string{filepath.Join("AAPL", ".AAPL_1min_2024-01-02.parquet.tmp-123"), "..lastday.json.tmp-9"}
+	for _, name := range append(keep, orphans...) {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := RemoveTemp(dir)
+	if err != nil || n != len(orphans) {
+		t.Fatalf("RemoveTemp = %d, %v; want %d", n, err, len(orphans))
+	}
+	for _, name := range keep {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s removed: %v", name, err)
+		}
+	}
+	for _, name := range orphans {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s left (%v)", name, err)
+		}
+	}
+	if n, err := RemoveTemp(filepath.Join(dir, "missing")); n != 0 || err != nil {
+		t.Errorf("missing root: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package fsutil
+
+import "errors"
+
+// FreeSpace is not supported on this platform; callers skip their check.
+func FreeSpace(path string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
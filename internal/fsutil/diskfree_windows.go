@@ -0,0 +1,19 @@
+//go:build windows
+
+package fsutil
+
+import "golang.org/x/sys/windows"
+
+// FreeSpace returns the bytes available to the caller on the volume holding
+// path.
+func FreeSpace(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, nil, nil); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
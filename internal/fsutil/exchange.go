@@ -0,0 +1,24 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// exchangeByRename swaps a and b through a temporary name next to b.
+func exchangeByRename(a, b string) error {
+	tmp := filepath.Join(filepath.Dir(b), "."+filepath.Base(b)+".swap")
+	if err := os.Rename(b, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(a, b); err != nil {
+		_ = os.Rename(tmp, b)
+		return err
+	}
+	if err := os.Rename(tmp, a); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(a))
+	syncDir(filepath.Dir(b))
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package fsutil
+
+import (
+	"errors"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// ExchangeDirs atomically swaps the paths a and b (both must exist on the
+// same filesystem): readers see either the old or the new content under
+// each path, never a missing one. Linux uses renameat2(RENAME_EXCHANGE).
+func ExchangeDirs(a, b string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+	if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL) {
+		return exchangeByRename(a, b) // kernel or filesystem without exchange support
+	}
+	if err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(a))
+	syncDir(filepath.Dir(b))
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package fsutil
+
+// ExchangeDirs swaps the paths a and b (both must exist on the same
+// filesystem). Without an atomic exchange syscall this is three renames, so
+// b is briefly missing; Linux builds swap atomically.
+func ExchangeDirs(a, b string) error {
+	return exchangeByRename(a, b)
+}
@@ -0,0 +1,36 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExchangeDirsSwapsContent(t *testing.T) {
+	base := t.TempDir()
+	a, b := filepath.Join(base, "a"), filepath.Join(base, "b")
+	for dir, content := range map[string]string{a: "new", b: "old"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "f"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ExchangeDirs(a, b); err != nil {
+		t.Fatalf("ExchangeDirs: %v", err)
+	}
+	for dir, want := range map[string]string{a: "old", b: "new"} {
+		got, err := os.ReadFile(filepath.Join(dir, "f"))
+		if err != nil || string(got) != want {
+			t.Errorf("%s: got %q, %v; want %q", filepath.Base(dir), got, err, want)
+		}
+	}
+	if err := exchangeByRename(a, b); err != nil {
+		t.Fatalf("exchangeByRename: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(b, "f")); string(got) != "old" {
+		t.Errorf("fallback swap: b holds %q, want %q", got, "old")
+	}
+}
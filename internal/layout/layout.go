@@ -0,0 +1,307 @@
+// Package layout renders the paths of bar files from a template, so the
+// store can follow a query engine's conventions (e.g. Hive partitions that
+// DuckDB, Spark and Polars prune on) instead of a hard-wired tree.
+//
+// A template is a slash-separated path relative to data.dir, e.g.
+//
+//	{source}/{class}/{ticker}/{ticker}_{timeframe}_{range}.{ext}
+//
+// with the placeholders
+//
+//	{source}    provider directory (Polygon)
+//	{class}     asset class (stocks, crypto, …)
+//	{adjusted}  true for split-adjusted bars, false for raw ones
+//	{kind}      bars, or actions for the corporate actions files
+//	{ticker}    symbol, escaped (see Escape)
+//	{timeframe} bar resolution, e.g. 5min (5min_raw for raw bars)
+//	{year} {month} {day}  period of the file's first bar day
+//	{range}     the file's days: 2024-01-02_to_2024-06-28, or its period
+//	            (2024-03-05, 2024-03, 2024) when bars are partitioned
+//	{ext}       format extension
+//
+// All files of a ticker live under one directory, which a split rewrite
+// swaps atomically; so the template has exactly one {ticker} directory,
+// only {source}, {class}, {adjusted} and {kind} before it, and a file name
+// starting with {ticker}_{timeframe}_ that contains {range} and ends in
+// .{ext}.
+//
+// A template without {adjusted} keeps raw series in a tree of their own
+// (Polygon/raw/stocks/…); one without {kind} saves the corporate actions in
+// the ticker directories of the adjusted bars.
+package layout
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Presets selectable by name instead of a template.
+const (
+	// Default is the classic tree: data/Polygon/stocks/AAPL/AAPL_5min_….parquet.
+	Default = "{source}/{class}/{ticker}/{ticker}_{timeframe}_{range}.{ext}"
+	// Hive names directories key=value, with the adjustment and a year as
+	// partitions and the corporate actions in a tree of their own:
+	// data/Polygon/kind=bars/class=stocks/adjusted=true/ticker=AAPL/year=2024/AAPL_5min_2024-03.parquet
+	// data/Polygon/kind=actions/class=stocks/ticker=AAPL/AAPL_actions.parquet
+	Hive = "{source}/kind={kind}/class={class}/adjusted={adjusted}/ticker={ticker}/year={year}/{ticker}_{timeframe}_{range}.{ext}"
+)
+
+var presets = map[string]string{"default": Default, "hive": Hive}
+
+// rawDir is inserted before the class directory of raw (unadjusted) series
+// when the template does not name {adjusted}, keeping them apart from
+// adjusted ones: Polygon/raw/stocks/….
+const rawDir = "raw"
+
+// Values of {kind}.
+const (
+	kindBars    = "bars"
+	kindActions = "actions"
+)
+
+var placeholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// placeholders allowed in each part of a template.
+var (
+	classVars  = []string{"source", "class", "adjusted", "kind"}
+	tickerVars = []string{"ticker"}
+	fileVars   = []string{"ticker", "timeframe", "year", "month", "day", "range", "ext"}
+	subdirVars = []string{"timeframe", "year", "month", "day"}
+)
+
+// Layout is a parsed template.
+type Layout struct {
+	class  []string // segments up to the ticker directory
+	ticker string   // the ticker directory segment
+	file   []string // segments below it; the last is the file name
+}
+
+// New parses spec, a preset name (default, hive) or a template. An empty
+// spec is the default layout.
+func New(spec string) (*Layout, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		spec = Default
+	}
+	if t, ok := presets[strings.ToLower(spec)]; ok {
+		spec = t
+	}
+	return Parse(spec)
+}
+
+// Parse parses and validates a template.
+func Parse(tmpl string) (*Layout, error) {
+	fail := func(format string, args ...any) (*Layout, error) {
+		return nil, fmt.Errorf("layout %q: "+format, append([]any{tmpl}, args...)...)
+	}
+	if strings.Contains(tmpl, `\`) || path.IsAbs(tmpl) || filepath.IsAbs(tmpl) {
+		return fail("must be a relative path with / separators")
+	}
+	segs := strings.Split(tmpl, "/")
+	at := -1
+	for i, s := range segs {
+		if s == "" || s == "." || s == ".." {
+			return fail("empty, . or .. path element")
+		}
+		for _, m := range placeholder.FindAllStringSubmatch(s, -1) {
+			if !slices.Contains(fileVars, m[1]) && !slices.Contains(classVars, m[1]) {
+				return fail("unknown placeholder {%s}", m[1])
+			}
+		}
+		if i < len(segs)-1 && strings.Contains(s, "{ticker}") {
+			if at >= 0 {
+				return fail("more than one {ticker} directory")
+			}
+			at = i
+		}
+	}
+	if at < 0 {
+		return fail("no {ticker} directory")
+	}
+	l := &Layout{class: segs[:at], ticker: segs[at], file: segs[at+1:]}
+
+	count := make(map[string]int)
+	for _, s := range l.class {
+		if !usesOnly(s, classVars) {
+			return fail("%q: only {source}, {class}, {adjusted} and {kind} may precede the ticker directory", s)
+		}
+		for _, m := range placeholder.FindAllStringSubmatch(s, -1) {
+			count[m[1]]++
+		}
+		// Actions files have no adjustment: their directories leave it out.
+		if strings.Contains(s, "{adjusted}") && !usesOnly(s, []string{"adjusted"}) {
+			return fail("%q: {adjusted} takes a directory of its own", s)
+		}
+	}
+	if count["class"] != 1 {
+		return fail("needs exactly one {class} directory before the ticker directory")
+	}
+	if count["adjusted"] > 1 || count["kind"] > 1 {
+		return fail("{adjusted} and {kind} may appear once each")
+	}
+	if !usesOnly(l.ticker, tickerVars) {
+		return fail("%q: the ticker directory takes no other placeholder", l.ticker)
+	}
+	for _, s := range l.file[:len(l.file)-1] {
+		if !usesOnly(s, subdirVars) {
+			return fail("%q: directories below the ticker take only {timeframe}, {year}, {month}, {day}", s)
+		}
+	}
+	name := l.file[len(l.file)-1]
+	if !usesOnly(name, fileVars) {
+		return fail("%q: the file name takes only {ticker}, {timeframe}, {year}, {month}, {day}, {range}, {ext}", name)
+	}
+	if !strings.HasPrefix(name, "{ticker}_{timeframe}_") || !strings.Contains(name, "{range}") || !strings.HasSuffix(name, ".{ext}") {
+		return fail("file name must be {ticker}_{timeframe}_…{range}….{ext}")
+	}
+	return l, nil
+}
+
+// usesOnly reports whether segment s holds no placeholder but vars.
+func usesOnly(s string, vars []string) bool {
+	for _, m := range placeholder.FindAllStringSubmatch(s, -1) {
+		if !slices.Contains(vars, m[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ClassDir returns the directory of a class's ticker directories of bars,
+// relative to data.dir: {adjusted} is false for raw series, or without it
+// raw series get their own tree.
+func (l *Layout) ClassDir(source, class string, raw bool) string {
+	r := strings.NewReplacer("{source}", Escape(source), "{class}", Escape(class),
+		"{adjusted}", strconv.FormatBool(!raw), "{kind}", kindBars)
+	named := l.names("adjusted")
+	segs := make([]string, 0, len(l.class)+1)
+	for _, s := range l.class {
+		if raw && !named && strings.Contains(s, "{class}") {
+			segs = append(segs, rawDir)
+		}
+		segs = append(segs, r.Replace(s))
+	}
+	return filepath.Join(segs...)
+}
+
+// ActionsDir returns the directory of a class's ticker directories of
+// corporate actions, relative to data.dir. With {kind} in the template it is
+// a tree of its own (kind=actions, without the {adjusted} directory);
+// otherwise it is the adjusted bars' class directory.
+func (l *Layout) ActionsDir(source, class string) string {
+	if !l.names("kind") {
+		return l.ClassDir(source, class, false)
+	}
+	r := strings.NewReplacer("{source}", Escape(source), "{class}", Escape(class), "{kind}", kindActions)
+	segs := make([]string, 0, len(l.class))
+	for _, s := range l.class {
+		if !strings.Contains(s, "{adjusted}") {
+			segs = append(segs, r.Replace(s))
+		}
+	}
+	return filepath.Join(segs...)
+}
+
+// names reports whether the directories before the ticker name placeholder v.
+func (l *Layout) names(v string) bool {
+	return slices.ContainsFunc(l.class, func(s string) bool { return strings.Contains(s, "{"+v+"}") })
+}
+
+// TickerDir returns the name of ticker's directory under its class directory.
+func (l *Layout) TickerDir(ticker string) string {
+	return strings.ReplaceAll(l.ticker, "{ticker}", Escape(ticker))
+}
+
+// File returns the path of a bar file below its ticker directory. from and
+// to are the first and last bar days; partition is the file period
+// (day, month, year) when bars are partitioned, otherwise "" or none.
+func (l *Layout) File(ticker, timeframe, ext string, from, to time.Time, partition string) string {
+	r := strings.NewReplacer(
+		"{ticker}", Escape(ticker),
+		"{timeframe}", timeframe,
+		"{year}", from.Format("2006"),
+		"{month}", from.Format("01"),
+		"{day}", from.Format("02"),
+		"{range}", Range(partition, from, to),
+		"{ext}", ext,
+	)
+	segs := make([]string, len(l.file))
+	for i, s := range l.file {
+		segs[i] = r.Replace(s)
+	}
+	return filepath.Join(segs...)
+}
+
+// Series returns the series name of bars of timeframe in file names and
+// progress keys: the timeframe, suffixed with "_raw" for unadjusted bars
+// (5min_raw), or just "raw" when timeframe is empty, so the raw and adjusted
+// series never share a name.
+func Series(timeframe string, raw bool) string {
+	switch {
+	case !raw:
+		return timeframe
+	case timeframe == "":
+		return "raw"
+	}
+	return timeframe + "_raw"
+}
+
+// FilePrefix returns the start of the file names of ticker's series of
+// timeframe (see BarFetcher.SaveBars).
+func FilePrefix(ticker, timeframe string) string {
+	return Escape(ticker) + "_" + timeframe + "_"
+}
+
+// periodLayouts maps each partition period to the date layout naming it.
+var periodLayouts = map[string]string{
+	"day":   "2006-01-02",
+	"month": "2006-01",
+	"year":  "2006",
+}
+
+// Range renders {range}: the period of from when partition is day, month
+// or year, otherwise the days from_to_to.
+func Range(partition string, from, to time.Time) string {
+	if layout, ok := periodLayouts[partition]; ok {
+		return from.Format(layout)
+	}
+	return from.Format("2006-01-02") + "_to_" + to.Format("2006-01-02")
+}
+
+// Period returns the finest period (day, month, year) the template names
+// below the ticker directory, or "" when it names none. Bars must be
+// partitioned at least that finely, or a file would hold bars of periods
+// its path does not name.
+func (l *Layout) Period() string {
+	all := strings.Join(l.file, "/")
+	for _, p := range []string{"day", "month", "year"} {
+		if strings.Contains(all, "{"+p+"}") {
+			return p
+		}
+	}
+	return ""
+}
+
+// Escape makes symbol safe as a path element on every platform and in Hive
+// partition values: bytes other than letters, digits, '.', '_' and '-' are
+// percent-encoded, as Hive does (X:BTCUSD → X%3ABTCUSD).
+func Escape(symbol string) string {
+	var b strings.Builder
+	for i := 0; i < len(symbol); i++ {
+		c := symbol[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '.' && i > 0, c == '_', c == '-':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package layout
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestDefaultLayoutKeepsClassicPaths(t *testing.T) {
+	l, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := filepath.Join(l.ClassDir("Polygon", "stocks", false), l.TickerDir("AAPL"),
+		l.File("AAPL", "5min", "parquet", day("2024-02-26"), day("2024-08-16"), "none"))
+	if want := filepath.FromSlash("Polygon/stocks/AAPL/AAPL_5min_2024-02-26_to_2024-08-16.parquet"); got != want {
+		t.Errorf("default path = %s, want %s", got, want)
+	}
+	if got, want := l.ClassDir("Polygon", "stocks", true), filepath.FromSlash("Polygon/raw/stocks"); got != want {
+		t.Errorf("raw class dir = %s, want %s", got, want)
+	}
+	if got, want := l.ActionsDir("Polygon", "stocks"), filepath.FromSlash("Polygon/stocks"); got != want {
+		t.Errorf("actions dir = %s, want the adjusted bars' %s", got, want)
+	}
+	if p := l.Period(); p != "" {
+		t.Errorf("default period = %q, want none", p)
+	}
+}
+
+func TestHiveLayout(t *testing.T) {
+	l, err := New("hive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := filepath.Join(l.ClassDir("Polygon", "crypto", false), l.TickerDir("X:BTCUSD"),
+		l.File("X:BTCUSD", "1min", "parquet", day("2024-03-01"), day("2024-03-31"), "month"))
+	want := filepath.FromSlash("Polygon/kind=bars/class=crypto/adjusted=true/ticker=X%3ABTCUSD/year=2024/X%3ABTCUSD_1min_2024-03.parquet")
+	if got != want {
+		t.Errorf("hive path = %s, want %s", got, want)
+	}
+	// Adjustment is a partition key, not a separate tree; actions have one.
+	for _, c := range []struct{ got, want string }{
+		{l.ClassDir("Polygon", "stocks", true), "Polygon/kind=bars/class=stocks/adjusted=false"},
+		{l.ActionsDir("Polygon", "stocks"), "Polygon/kind=actions/class=stocks"},
+	} {
+		if c.got != filepath.FromSlash(c.want) {
+			t.Errorf("dir = %s, want %s", c.got, c.want)
+		}
+	}
+	if p := l.Period(); p != "year" {
+		t.Errorf("hive period = %q, want year", p)
+	}
+}
+
+func TestParseRejectsUnsafeTemplates(t *testing.T) {
+	for _, tmpl := range []string{
+		"{class}/{ticker}_{timeframe}_{range}.{ext}",                        // no ticker directory
+		"{source}/{ticker}/{ticker}_{timeframe}_{range}.{ext}",              // no class directory
+		"{class}/{year}/{ticker}/{ticker}_{timeframe}_{range}.{ext}",        // period above the ticker
+		"{class}/{ticker}/{ticker}_{timeframe}.{ext}",                       // no range: chunks collide
+		"{class}/{ticker}/{timeframe}_{ticker}_{range}.{ext}",               // series prefix
+		"{class}/{ticker}/{ticker}_{timeframe}_{range}.{ext}/../x",          // escapes
+		"/{class}/{ticker}/{ticker}_{timeframe}_{range}.{ext}",              // absolute
+		"{class}/{ticker}/{ticker}_{timeframe}_{range}_{adjusted}.{ext}",    // adjustment below the ticker
+		"{class}/{ticker}/{ticker}_{timeframe}_{range}_{bogus}.{ext}",       // unknown placeholder
+		"{class}_{adjusted}/{ticker}/{ticker}_{timeframe}_{range}.{ext}",    // {adjusted} shares a directory
+		"{kind}/{class}/{kind}/{ticker}/{ticker}_{timeframe}_{range}.{ext}", // {kind} twice
+	} {
+		if _, err := Parse(tmpl); err == nil {
+			t.Errorf("Parse(%q) accepted", tmpl)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	for in, want := range map[string]string{
+		"AAPL":     "AAPL",
+		"BRK.B":    "BRK.B",
+		"X:BTCUSD": "X%3ABTCUSD",
+		"A/B=C":    "A%2FB%3DC",
+		"..":       "%2E.",
+	} {
+		if got := Escape(in); got != want {
+			t.Errorf("Escape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSeries(t *testing.T) {
+	for _, c := range []struct {
+		timeframe string
+		raw       bool
+		want      string
+	}{
+		{"5min", false, "5min"},
+		{"5min", true, "5min_raw"},
+		{"", false, ""},
+		{"", true, "raw"}, // never the adjusted series' name
+	} {
+		if got := Series(c.timeframe, c.raw); got != c.want {
+			t.Errorf("Series(%q, %v) = %q, want %q", c.timeframe, c.raw, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package membership keeps the constituent history of index groups (sp500,
+// nasdaq100, …), so backtests can ask which tickers were in an index on a
+// given date instead of seeing only today's members.
+//
+// Each recorded group gets a directory with one snapshot per day and an
+// interval file:
+//
+//	membership/sp500/2025-03-14.json     tickers on that day
+//	membership/sp500/intervals.json      ticker → effective from/to dates
+//
+// Dates are the days snapshots were taken, so interval bounds are exact to
+// the snapshot cadence (one crawl cycle). The first snapshot of a group opens
+// an interval for every member on that day; earlier membership is unknown.
+package membership
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"us-data/internal/fsutil"
+)
+
+const dateLayout = "2006-01-02"
+
+// Interval is one continuous membership of Ticker. From and To are inclusive
+// YYYY-MM-DD dates; To is empty while the ticker is still a member.
+type Interval struct {
+	Ticker string `json:"ticker"`
+	From   string `json:"from"`
+	To     string `json:"to,omitempty"`
+}
+
+// open reports whether the interval has not ended.
+func (iv Interval) open() bool { return iv.To == "" }
+
+// Contains reports whether the ticker was a member on day.
+func (iv Interval) Contains(day time.Time) bool {
+	d := day.Format(dateLayout)
+	return iv.From <= d && (iv.open() || d <= iv.To)
+}
+
+// History is the content of a group's intervals.json.
+type History struct {
+	Group     string     `json:"group"`
+	Updated   string     `json:"updated"` // date of the last recorded snapshot
+	Intervals []Interval `json:"intervals"`
+}
+
+// MembersOn returns the tickers that were members on day, sorted.
+func (h History) MembersOn(day time.Time) []string {
+	var out []string
+	for _, iv := range h.Intervals {
+		if iv.Contains(day) && !slices.Contains(out, iv.Ticker) {
+			out = append(out, iv.Ticker)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// RemovedSince returns the tickers whose membership ended on or after since
+// and which are not members again, sorted.
+func (h History) RemovedSince(since time.Time) []string {
+	s := since.Format(dateLayout)
+	current := make(map[string]bool)
+	for _, iv := range h.Intervals {
+		if iv.open() {
+			current[iv.Ticker] = true
+		}
+	}
+	var out []string
+	for _, iv := range h.Intervals {
+		if !iv.open() && iv.To >= s && !current[iv.Ticker] && !slices.Contains(out, iv.Ticker) {
+			out = append(out, iv.Ticker)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Change lists the tickers that joined or left a group in one snapshot.
+// First marks the group's first snapshot, where every member is Added.
+type Change struct {
+	Added   []string
+	Removed []string
+	First   bool
+}
+
+// Store reads and writes group histories under a root directory
+// (e.g. data/Polygon/membership). Not safe for concurrent use.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store rooted at dir; directories are created on write.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) groupDir(group string) string {
+	return filepath.Join(s.dir, strings.ToLower(group))
+}
+
+// Load returns the history of group; a group never recorded has none.
+func (s *Store) Load(group string) (History, error) {
+	h := History{Group: strings.ToLower(group)}
+	data, err := os.ReadFile(filepath.Join(s.groupDir(group), "intervals.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return h, fmt.Errorf("parse %s intervals: %w", group, err)
+	}
+	return h, nil
+}
+
+// Record writes the snapshot of group's members on day and updates its
+// intervals: new members open an interval from day, members no longer listed
+// have theirs closed on the day before. Recording a day again replaces that
+// day's snapshot. Snapshots older than the last recorded day are ignored.
+func (s *Store) Record(group string, day time.Time, tickers []string) (Change, error) {
+	h, err := s.Load(group)
+	if err != nil {
+		return Change{}, err
+	}
+	d := day.Format(dateLayout)
+	if h.Updated > d {
+		return Change{}, nil
+	}
+
+	members := slices.Clone(tickers)
+	slices.Sort(members)
+	members = slices.Compact(members)
+
+	snap, err := json.MarshalIndent(struct {
+		Group   string   `json:"group"`
+		Date    string   `json:"date"`
+		Tickers []string `json:"tickers"`
+	}{h.Group, d, members}, "", "  ")
+	if err != nil {
+		return Change{}, err
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(s.groupDir(group), d+".json"), snap, 0o644); err != nil {
+		return Change{}, fmt.Errorf("write %s snapshot: %w", group, err)
+	}
+
+	change := h.apply(day, members)
+	h.Updated = d
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return Change{}, err
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(s.groupDir(group), "intervals.json"), data, 0o644); err != nil {
+		return Change{}, fmt.Errorf("write %s intervals: %w", group, err)
+	}
+	return change, nil
+}
+
+// apply updates the intervals for members (sorted, unique) as of day.
+func (h *History) apply(day time.Time, members []string) Change {
+	d := day.Format(dateLayout)
+	prev := day.AddDate(0, 0, -1).Format(dateLayout)
+
+	change := Change{First: h.Updated == ""}
+	open := make(map[string]bool)
+	kept := h.Intervals[:0]
+	for _, iv := range h.Intervals {
+		if iv.open() {
+			if _, ok := slices.BinarySearch(members, iv.Ticker); !ok {
+				change.Removed = append(change.Removed, iv.Ticker)
+				if prev < iv.From {
+					continue // joined and left on the same day: never effective
+				}
+				iv.To = prev
+			} else {
+				open[iv.Ticker] = true
+			}
+		}
+		kept = append(kept, iv)
+	}
+	h.Intervals = kept
+	for _, t := range members {
+		if open[t] {
+			continue
+		}
+		change.Added = append(change.Added, t)
+		// Back the day after leaving (a re-recorded day): continue that interval.
+		if i := slices.IndexFunc(h.Intervals, func(iv Interval) bool { return iv.Ticker == t && iv.To == prev }); i >= 0 {
+			h.Intervals[i].To = ""
+			continue
+		}
+		h.Intervals = append(h.Intervals, Interval{Ticker: t, From: d})
+	}
+	slices.SortFunc(h.Intervals, func(a, b Interval) int {
+		if c := strings.Compare(a.Ticker, b.Ticker); c != 0 {
+			return c
+		}
+		return strings.Compare(a.From, b.From)
+	})
+	slices.Sort(change.Removed)
+	return change
+}
@@ -0,0 +1,83 @@
+package membership
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRecordBuildsIntervals(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir)
+
+	if c, err := s.Record("sp500", day("2025-03-10"), []string{"AAPL", "MSFT", "WBA"}); err != nil || !c.First {
+		t.Fatalf("first record: change=%+v err=%v", c, err)
+	}
+	c, err := s.Record("sp500", day("2025-03-11"), []string{"AAPL", "MSFT", "TKO"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(c.Added, []string{"TKO"}) || !slices.Equal(c.Removed, []string{"WBA"}) || c.First {
+		t.Errorf("change = %+v, want +TKO -WBA", c)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sp500", "2025-03-11.json")); err != nil {
+		t.Errorf("snapshot missing: %v", err)
+	}
+
+	h, err := s.Load("sp500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.MembersOn(day("2025-03-10")); !slices.Equal(got, []string{"AAPL", "MSFT", "WBA"}) {
+		t.Errorf("members on 03-10 = %v", got)
+	}
+	if got := h.MembersOn(day("2025-03-11")); !slices.Equal(got, []string{"AAPL", "MSFT", "TKO"}) {
+		t.Errorf("members on 03-11 = %v", got)
+	}
+	if got := h.RemovedSince(day("2025-03-01")); !slices.Equal(got, []string{"WBA"}) {
+		t.Errorf("removed since 03-01 = %v", got)
+	}
+	if got := h.RemovedSince(day("2025-03-11")); len(got) != 0 {
+		t.Errorf("removed since 03-11 = %v, want none (WBA left on 03-10)", got)
+	}
+}
+
+func TestRecordSameDayReplacesSnapshot(t *testing.T) {
+	s := NewStore(t.TempDir())
+	mustRecord := func(d string, tickers ...string) {
+		t.Helper()
+		if _, err := s.Record("dji", day(d), tickers); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustRecord("2025-03-10", "AAPL", "INTC")
+	mustRecord("2025-03-11", "AAPL", "NVDA")
+	mustRecord("2025-03-11", "AAPL", "INTC") // corrected source on re-run
+
+	h, err := s.Load("dji")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Interval{
+		{Ticker: "AAPL", From: "2025-03-10"},
+		{Ticker: "INTC", From: "2025-03-10"},
+	}
+	if !slices.Equal(h.Intervals, want) {
+		t.Errorf("intervals = %+v, want %+v", h.Intervals, want)
+	}
+
+	// An older snapshot never rewrites newer history.
+	if c, err := s.Record("dji", day("2025-03-01"), nil); err != nil || len(c.Removed) != 0 {
+		t.Errorf("stale record: change=%+v err=%v", c, err)
+	}
+}
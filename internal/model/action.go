@@ -0,0 +1,41 @@
+package model
+
+// Corporate action types (CorporateAction.Type).
+const (
+	ActionSplit    = "split"
+	ActionDividend = "dividend"
+)
+
+// CorporateAction is one split or cash dividend of a ticker, flattened into a
+// single row type so both kinds share one file per ticker in any format.
+//
+// Date is the day the action takes effect on prices: the execution date of a
+// split, the ex-dividend date of a dividend. Fields of the other kind are zero.
+type CorporateAction struct {
+	ID     string `json:"id" parquet:"id"`
+	Type   string `json:"type" parquet:"type"` // split | dividend
+	Ticker string `json:"ticker" parquet:"ticker"`
+	Date   string `json:"date" parquet:"date"` // YYYY-MM-DD
+
+	// Split: SplitTo new shares for every SplitFrom old ones (4-for-1: from 1, to 4).
+	SplitFrom float64 `json:"split_from,omitempty" parquet:"split_from,optional"`
+	SplitTo   float64 `json:"split_to,omitempty" parquet:"split_to,optional"`
+
+	// Dividend.
+	CashAmount      float64 `json:"cash_amount,omitempty" parquet:"cash_amount,optional"`
+	Currency        string  `json:"currency,omitempty" parquet:"currency,optional"`
+	DividendType    string  `json:"dividend_type,omitempty" parquet:"dividend_type,optional"` // CD regular, SC special, LT/ST capital gains
+	Frequency       int64   `json:"frequency,omitempty" parquet:"frequency,optional"`         // payments per year; 0 = one-time
+	DeclarationDate string  `json:"declaration_date,omitempty" parquet:"declaration_date,optional"`
+	RecordDate      string  `json:"record_date,omitempty" parquet:"record_date,optional"`
+	PayDate         string  `json:"pay_date,omitempty" parquet:"pay_date,optional"`
+}
+
+// SplitRatio returns the price divisor of a split (SplitTo / SplitFrom), or 1
+// when the action is not a valid split.
+func (a CorporateAction) SplitRatio() float64 {
+	if a.Type != ActionSplit || a.SplitFrom <= 0 || a.SplitTo <= 0 {
+		return 1
+	}
+	return a.SplitTo / a.SplitFrom
+}
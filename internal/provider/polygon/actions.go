@@ -0,0 +1,194 @@
+package polygon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"us-data/internal/layout"
+	"us-data/internal/model"
+	"us-data/internal/saver"
+)
+
+// splitsResponse is one page of GET /v3/reference/splits.
+type splitsResponse struct {
+	Results []struct {
+		ID            string  `json:"id"`
+		Ticker        string  `json:"ticker"`
+		ExecutionDate string  `json:"execution_date"`
+		SplitFrom     float64 `json:"split_from"`
+		SplitTo       float64 `json:"split_to"`
+	} `json:"results"`
+	NextURL string `json:"next_url"`
+}
+
+// dividendsResponse is one page of GET /v3/reference/dividends.
+type dividendsResponse struct {
+	Results []struct {
+		ID              string  `json:"id"`
+		Ticker          string  `json:"ticker"`
+		ExDividendDate  string  `json:"ex_dividend_date"`
+		CashAmount      float64 `json:"cash_amount"`
+		Currency        string  `json:"currency"`
+		DividendType    string  `json:"dividend_type"`
+		Frequency       int64   `json:"frequency"`
+		DeclarationDate string  `json:"declaration_date"`
+		RecordDate      string  `json:"record_date"`
+		PayDate         string  `json:"pay_date"`
+	} `json:"results"`
+	NextURL string `json:"next_url"`
+}
+
+// CrawlActions fetches the splits and dividends of ticker that take effect in
+// [from, to] (execution / ex-dividend date), sorted by date. A zero from
+// fetches the whole history. Requests wait on apiKey's rate-limit bucket.
+func (c *Crawler) CrawlActions(ctx context.Context, ticker, apiKey string, from, to time.Time) ([]model.CorporateAction, error) {
+	client := refHTTPClient()
+	out, err := c.fetchSplits(ctx, client, ticker, apiKey, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("splits %s: %w", ticker, err)
+	}
+
+	pageURL := c.actionsURL("/v3/reference/dividends", "ex_dividend_date", ticker, from, to)
+	for pageURL != "" {
+		var page dividendsResponse
+		if err := c.fetchReferencePage(ctx, client, pageURL, apiKey, &page); err != nil {
+			return nil, fmt.Errorf("dividends %s: %w", ticker, err)
+		}
+		for _, r := range page.Results {
+			out = append(out, model.CorporateAction{
+				ID: r.ID, Type: model.ActionDividend, Ticker: ticker, Date: r.ExDividendDate,
+				CashAmount: r.CashAmount, Currency: r.Currency,
+				DividendType: r.DividendType, Frequency: r.Frequency,
+				DeclarationDate: r.DeclarationDate, RecordDate: r.RecordDate, PayDate: r.PayDate,
+			})
+		}
+		pageURL = page.NextURL
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out, nil
+}
+
+// CrawlSplits fetches the splits of all tickers executed in [from, to], sorted
+// by date: one request per page instead of one per ticker.
+func (c *Crawler) CrawlSplits(ctx context.Context, apiKey string, from, to time.Time) ([]model.CorporateAction, error) {
+	out, err := c.fetchSplits(ctx, refHTTPClient(), "", apiKey, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("splits: %w", err)
+	}
+	return out, nil
+}
+
+// fetchSplits pages through /v3/reference/splits; an empty ticker lists every
+// ticker.
+func (c *Crawler) fetchSplits(ctx context.Context, client *http.Client, ticker, apiKey string, from, to time.Time) ([]model.CorporateAction, error) {
+	var out []model.CorporateAction
+	pageURL := c.actionsURL("/v3/reference/splits", "execution_date", ticker, from, to)
+	for pageURL != "" {
+		var page splitsResponse
+		if err := c.fetchReferencePage(ctx, client, pageURL, apiKey, &page); err != nil {
+			return nil, err
+		}
+		for _, r := range page.Results {
+			out = append(out, model.CorporateAction{
+				ID: r.ID, Type: model.ActionSplit, Ticker: r.Ticker, Date: r.ExecutionDate,
+				SplitFrom: r.SplitFrom, SplitTo: r.SplitTo,
+			})
+		}
+		pageURL = page.NextURL
+	}
+	return out, nil
+}
+
+// actionsURL builds the first page URL of a reference endpoint filtered by
+// dateField within [from, to] and, unless empty, by ticker.
+func (c *Crawler) actionsURL(path, dateField, ticker string, from, to time.Time) string {
+	q := url.Values{}
+	if ticker != "" {
+		q.Set("ticker", ticker)
+	}
+	if !from.IsZero() {
+		q.Set(dateField+".gte", from.Format("2006-01-02"))
+	}
+	q.Set(dateField+".lte", to.Format("2006-01-02"))
+	q.Set("order", "asc")
+	q.Set("sort", dateField)
+	q.Set("limit", "1000")
+	return c.baseURL() + path + "?" + q.Encode()
+}
+
+// fetchReferencePage GETs one page of a paginated reference endpoint into v.
+// Non-200 responses are *APIError (see newStatusError).
+func (c *Crawler) fetchReferencePage(ctx context.Context, client *http.Client, pageURL, apiKey string, v any) error {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return fmt.Errorf("parse URL: %w", err)
+	}
+	q := u.Query()
+	if q.Get("apiKey") == "" {
+		q.Set("apiKey", apiKey)
+	}
+	u.RawQuery = q.Encode()
+
+	if err := c.waitForKey(ctx, apiKey); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("API call: %w", redactKey(err))
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newStatusError(resp, body)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return &APIError{Kind: ErrDecode, StatusCode: resp.StatusCode, Err: err}
+	}
+	return nil
+}
+
+// SaveActions merges actions into the ticker's actions file in dir/ticker/
+// (the ticker directory as named by the Layout) using the configured
+// PacketSaver: one file per ticker that grows every cycle, where an action
+// replaces a saved one with the same ID (see saver.MergeActions).
+//
+// File name format: {ticker}_actions.{ext}
+func (c *Crawler) SaveActions(dir, ticker string, actions []model.CorporateAction) error {
+	if dir == "" || c.PacketSaver == nil || len(actions) == 0 {
+		return nil
+	}
+	tickerDir := c.tickerDir(dir, ticker)
+	ext := c.PacketSaver.Extension()
+	path := filepath.Join(tickerDir, layout.Escape(ticker)+"_actions."+ext)
+
+	saved, err := c.PacketSaver.LoadActions(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("read %s: %w", path, err) // never overwrite what could not be read
+	}
+	if err := os.MkdirAll(tickerDir, 0755); err != nil {
+		return err
+	}
+	merged := saver.MergeActions(saved, actions)
+	if err := c.PacketSaver.SaveActions(merged, path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	slog.Info("save ok", "ticker", ticker, "format", ext, "path", path, "actions", len(actions), "total", len(merged))
+	return nil
+}
@@ -0,0 +1,153 @@
+package polygon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"us-data/internal/model"
+	"us-data/internal/saver"
+)
+
+func TestActionsURL(t *testing.T) {
+	c := &Crawler{BaseURL: "http://api.test"}
+	from := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 28, 23, 59, 59, 0, time.UTC)
+
+	u, err := url.Parse(c.actionsURL("/v3/reference/splits", "execution_date", "BRK.B", from, to))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "api.test" || u.Path != "/v3/reference/splits" {
+		t.Errorf("URL = %s", u)
+	}
+	want := map[string]string{
+		"ticker": "BRK.B", "execution_date.gte": "2024-01-02", "execution_date.lte": "2024-06-28",
+		"order": "asc", "sort": "execution_date", "limit": "1000",
+	}
+	q := u.Query()
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s = %q, want %q", k, q.Get(k), v)
+		}
+	}
+	if len(q) != len(want) {
+		t.Errorf("query = %v, want %v", q, want)
+	}
+
+	// Whole history of every ticker: no lower bound, no ticker filter.
+	q = mustQuery(t, c.actionsURL("/v3/reference/splits", "execution_date", "", time.Time{}, to))
+	if q.Has("ticker") || q.Has("execution_date.gte") {
+		t.Errorf("query = %v, want neither ticker nor a lower bound", q)
+	}
+}
+
+func mustQuery(t *testing.T, raw string) url.Values {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
+
+func TestCrawlActionsFollowsNextURL(t *testing.T) {
+	var srv *httptest.Server
+	pages := 0
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		if got := r.URL.Query().Get("apiKey"); got != "key" {
+			t.Errorf("%s: apiKey = %q", r.URL, got)
+		}
+		var body any
+		switch {
+		case r.URL.Path == "/v3/reference/splits" && r.URL.Query().Get("cursor") == "":
+			if r.URL.Query().Get("ticker") != "AAPL" {
+				t.Errorf("splits query = %v", r.URL.Query())
+			}
+			body = map[string]any{
+				"results":  []map[string]any{{"id": "s1", "ticker": "AAPL", "execution_date": "2020-08-31", "split_from": 1, "split_to": 4}},
+				"next_url": srv.URL + "/v3/reference/splits?cursor=p2",
+			}
+		case r.URL.Path == "/v3/reference/splits":
+			body = map[string]any{
+				"results": []map[string]any{{"id": "s0", "ticker": "AAPL", "execution_date": "2014-06-09", "split_from": 1, "split_to": 7}},
+			}
+		case r.URL.Path == "/v3/reference/dividends":
+			body = map[string]any{
+				"results": []map[string]any{{"id": "d1", "ticker": "AAPL", "ex_dividend_date": "2024-05-10", "cash_amount": 0.25, "currency": "USD"}},
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer srv.Close()
+
+	c := &Crawler{BaseURL: srv.URL}
+	got, err := c.CrawlActions(context.Background(), "AAPL", "key", time.Time{}, time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 3 {
+		t.Errorf("requests = %d, want 3 (two split pages, one dividend page)", pages)
+	}
+	var ids []string
+	for _, a := range got {
+		ids = append(ids, a.ID)
+	}
+	if len(ids) != 3 || ids[0] != "s0" || ids[1] != "s1" || ids[2] != "d1" {
+		t.Errorf("actions = %v, want [s0 s1 d1] in date order", ids)
+	}
+}
+
+func TestCrawlActionsReportsAPIErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"status":"NOT_AUTHORIZED"}`))
+	}))
+	defer srv.Close()
+
+	c := &Crawler{BaseURL: srv.URL}
+	_, err := c.CrawlActions(context.Background(), "AAPL", "key", time.Time{}, time.Now())
+	var ae *APIError
+	if !errors.As(err, &ae) || ae.StatusCode != http.StatusForbidden {
+		t.Errorf("err = %v, want an *APIError with status 403", err)
+	}
+}
+
+func TestSaveActionsMergesIntoOneFile(t *testing.T) {
+	dir := t.TempDir()
+	c := &Crawler{PacketSaver: saver.CSVSaver{}}
+
+	for _, batch := range [][]model.CorporateAction{
+		{
+			{ID: "s0", Type: model.ActionSplit, Ticker: "AAPL", Date: "2014-06-09", SplitFrom: 1, SplitTo: 7},
+			{ID: "d1", Type: model.ActionDividend, Ticker: "AAPL", Date: "2024-02-09", CashAmount: 0.23},
+		},
+		{
+			{ID: "d1", Type: model.ActionDividend, Ticker: "AAPL", Date: "2024-02-09", CashAmount: 0.24}, // corrected
+			{ID: "d2", Type: model.ActionDividend, Ticker: "AAPL", Date: "2024-05-10", CashAmount: 0.25},
+		},
+		nil, // a cycle without new actions leaves the file alone
+	} {
+		if err := c.SaveActions(dir, "AAPL", batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := c.PacketSaver.LoadActions(filepath.Join(dir, "AAPL", "AAPL_actions.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].ID != "s0" || got[1].ID != "d1" || got[1].CashAmount != 0.24 || got[2].ID != "d2" {
+		t.Errorf("actions = %+v, want s0, the corrected d1, d2", got)
+	}
+}
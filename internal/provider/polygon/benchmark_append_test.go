@@ -17,7 +17,7 @@ const (
 func mockCrawl(cooldown time.Duration, withPrealloc bool) func(string, string) ([]model.Bar, error) {
 	from := time.Now().AddDate(-2, 0, 0)
 	to := time.Now()
-	cap := estimatedBars(from, to)
+	cap := (&Crawler{}).estimatedBars(from, to)
 	return func(ticker, key string) ([]model.Bar, error) {
 		time.Sleep(cooldown)
 		var bars []model.Bar
@@ -132,7 +132,7 @@ func BenchmarkAppendNoPrealloc(b *testing.B) {
 func BenchmarkAppendPrealloc(b *testing.B) {
 	from := time.Now().AddDate(-2, 0, 0)
 	to := time.Now()
-	cap := estimatedBars(from, to)
+	cap := (&Crawler{}).estimatedBars(from, to)
 	for i := 0; i < b.N; i++ {
 		allBars := make([]model.Bar, 0, cap)
 		for j := 0; j < benchBars2Years; j++ {
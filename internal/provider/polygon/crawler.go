@@ -1,9 +1,12 @@
 package polygon
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -11,8 +14,12 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"us-data/internal/calendar"
+	"us-data/internal/ctxutil"
+	"us-data/internal/layout"
 	"us-data/internal/model"
 	"us-data/internal/saver"
 )
@@ -22,9 +29,6 @@ const (
 
 	// maxLimit is the Polygon hard cap on results per request.
 	maxLimit = 50000
-
-	// KeyCooldownSec: Polygon 5 req/min => 12s between requests per key
-	KeyCooldownSec = 12
 )
 
 // barsPerDayBase is the maximum number of bars per calendar day for each
@@ -61,9 +65,28 @@ type Crawler struct {
 	client        *http.Client
 	SavePacketDir string
 	PacketSaver   saver.PacketSaver // When non-nil, used to persist raw packets.
-	SavePerDay    bool              // When true, saves one file per day; otherwise a single range file.
+	Layout        *layout.Layout    // bar file paths below the class directory (default: layout.Default)
 	Timespan      string            // minute | hour | day | week | month (default: minute)
 	Multiplier    int               // timeframe multiplier, e.g. 1, 5, 15 (default: 1)
+	Retry         *RetryPolicy      // per-request retry policy (default: DefaultRetryPolicy)
+
+	// RateLimiter is the per-key budget every request of this Crawler waits
+	// on (aggregates, reference, validation); nil never waits.
+	RateLimiter *RateLimiter
+	// Details caches ticker reference details (see GetTickerDetails); nil
+	// sends every lookup to the API.
+	Details *TickerDetailsCache
+	// BaseURL is the API root every request goes to; "" is the Polygon API.
+	BaseURL string
+
+	files pathLocks // serializes merges into one partition file
+}
+
+func (c *Crawler) retryPolicy() RetryPolicy {
+	if c.Retry != nil {
+		return *c.Retry
+	}
+	return DefaultRetryPolicy
 }
 
 func (c *Crawler) timespan() string {
@@ -81,16 +104,29 @@ func (c *Crawler) multiplier() int {
 }
 
 // timespanLabel returns a compact label for use in file names.
+func (c *Crawler) timespanLabel() string {
+	return TimeframeLabel(c.timespan(), c.multiplier())
+}
+
+// TimeframeLabel returns the compact label of a timespan × multiplier, used in
+// file names and progress keys.
 //
 //	minute/1  → "1min"   minute/5  → "5min"
 //	hour/1    → "1h"     day/1     → "1d"
 //	week/1    → "1wk"    month/1   → "1mo"
-func (c *Crawler) timespanLabel() string {
-	suffix, ok := timespanSuffix[c.timespan()]
+func TimeframeLabel(timespan string, multiplier int) string {
+	timespan = strings.ToLower(strings.TrimSpace(timespan))
+	if timespan == "" {
+		timespan = "minute"
+	}
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+	suffix, ok := timespanSuffix[timespan]
 	if !ok {
-		suffix = c.timespan()
+		suffix = timespan
 	}
-	return fmt.Sprintf("%d%s", c.multiplier(), suffix)
+	return fmt.Sprintf("%d%s", multiplier, suffix)
 }
 
 // maxChunkDays is the upper bound for a single API request window.
@@ -99,14 +135,17 @@ func (c *Crawler) timespanLabel() string {
 // reason to request more than the longest possible job window.
 const maxChunkDays = 730
 
-// maxDaysPerChunk returns the maximum calendar days per API request so that
+// MaxDaysPerChunk returns the maximum calendar days per API request so that
 // the bar count stays under maxLimit.
 //
 //	days = floor(maxLimit × multiplier / barsPerDayBase[timespan])
 //
 // The result is capped at maxChunkDays (730) so chunks never exceed the
 // default 2-year backfill window.
-func (c *Crawler) maxDaysPerChunk() int {
+//
+// Exported so the crawl engine can split long ranges into chunk-level jobs
+// with exactly the same boundaries CrawlBarsWithKey would use.
+func (c *Crawler) MaxDaysPerChunk() int {
 	base, ok := barsPerDayBase[c.timespan()]
 	if !ok || base <= 0 {
 		return maxChunkDays
@@ -121,7 +160,9 @@ func (c *Crawler) maxDaysPerChunk() int {
 	return d
 }
 
-// estimatedBars returns a pre-allocation capacity for [from, to] to avoid slice growth.
+// estimatedBars returns a pre-allocation capacity for [from, to] to avoid slice
+// growth. Callers cap it at one response (maxLimit): a multi-year 1-minute
+// estimate is over a million bars.
 //
 // barsPerDayBase already uses worst-case density (1440 for minute = crypto/forex),
 // so stocks (~960 bars/day) naturally get ~50% headroom before the +20% buffer.
@@ -155,47 +196,196 @@ func (c *Crawler) Close() error {
 
 // SaveBars persists bars into dir/ticker/ using the configured PacketSaver.
 // dir is the asset-class-specific directory (e.g. data/Polygon/stocks).
-// If dir is empty or PacketSaver is nil, the call is a no-op.
+// If dir is empty or PacketSaver is nil, the call is a no-op. A write error
+// leaves no partial file behind.
+//
+// The path below dir follows the Layout; the default one saves to
+// dir/{ticker}/ with
+//
+// File name format: {ticker}_{timespan}_{from}_to_{to}.{ext}  (partition none)
 //
-// File name format: {ticker}_{timespan}_{from}.{ext}  (per-day)
-//                   {ticker}_{timespan}_{from}_to_{to}.{ext}  (range)
-func (c *Crawler) SaveBars(dir, ticker string, from, to time.Time, bars []model.Bar) {
+//	{ticker}_{timespan}_{YYYY-MM-DD}.{ext}     (day)
+//	{ticker}_{timespan}_{YYYY-MM}.{ext}        (month)
+//	{ticker}_{timespan}_{YYYY}.{ext}           (year)
+//
+// Raw (adjusted=false) bars get "_raw" after the timespan, e.g. AAPL_5min_raw_….
+// The ticker is escaped in paths (see layout.Escape): X:BTCUSD → X%3ABTCUSD.
+//
+// partition is the caller's: "" or none names the file after [from, to].
+// With day, month or year, from names the period and bars must all fall into
+// it (the caller splits them); bars already saved in the period's file are
+// kept and merged with the new ones by timestamp. Every such save reads and
+// rewrites the whole period file, so the bytes written per increment grow
+// with the period: a daily increment of minute bars rewrites the month's
+// bars under month partitions.
+func (c *Crawler) SaveBars(dir, ticker string, adjusted bool, partition string, from, to time.Time, bars []model.Bar) error {
 	if dir == "" || c.PacketSaver == nil || len(bars) == 0 {
-		return
+		return nil
+	}
+	partition = strings.ToLower(strings.TrimSpace(partition))
+	path := c.barPath(dir, ticker, adjusted, partition, from, to)
+	if partitioned(partition) {
+		defer c.files.lock(path)()
+		saved, err := c.PacketSaver.LoadBars(path)
+		switch {
+		case err == nil:
+			bars = saver.MergeBars(saved, bars)
+		case !errors.Is(err, fs.ErrNotExist):
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+	}
+	w, err := c.openBars(path, ticker)
+	if err != nil {
+		return err
+	}
+	if err := w.Append(bars); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Commit()
+}
+
+// OpenBars starts one streamed bar file in dir/ticker/ covering [from, to],
+// named as by an unpartitioned SaveBars: chunks are appended as they are
+// fetched, and the file is complete once committed. Without a PacketSaver
+// every write is discarded.
+func (c *Crawler) OpenBars(dir, ticker string, adjusted bool, from, to time.Time) (saver.BarWriter, error) {
+	if dir == "" || c.PacketSaver == nil {
+		return discardWriter{}, nil
+	}
+	return c.openBars(c.barPath(dir, ticker, adjusted, "", from, to), ticker)
+}
+
+// openBars starts the bar file at path.
+func (c *Crawler) openBars(path, ticker string) (saver.BarWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("mkdir %s: %w", filepath.Dir(path), err)
+	}
+	w, err := c.PacketSaver.OpenBars(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return &loggedWriter{BarWriter: w, ticker: ticker, path: path, format: c.PacketSaver.Extension()}, nil
+}
+
+// partitioned reports whether bar files of partition hold one period each.
+func partitioned(partition string) bool {
+	switch partition {
+	case "day", "month", "year":
+		return true
+	}
+	return false
+}
+
+// defaultLayout is the Layout of a Crawler without one.
+var defaultLayout, _ = layout.New(layout.Default)
+
+func (c *Crawler) layout() *layout.Layout {
+	if c.Layout != nil {
+		return c.Layout
+	}
+	return defaultLayout
+}
+
+// baseURL returns the API root of c (see BaseURL).
+func (c *Crawler) baseURL() string {
+	if c.BaseURL == "" {
+		return polygonBaseURL
 	}
-	tickerDir := filepath.Join(dir, ticker)
-	if err := os.MkdirAll(tickerDir, 0755); err != nil {
-		slog.Error("save: mkdir failed", "ticker", ticker, "dir", tickerDir, "err", err)
-		return
+	return c.BaseURL
+}
+
+// tickerDir returns the directory of ticker under its class directory.
+func (c *Crawler) tickerDir(dir, ticker string) string {
+	return filepath.Join(dir, c.layout().TickerDir(ticker))
+}
+
+// barPath returns the path of ticker's bar file for [from, to] in dir, or
+// for from's period when partitioned.
+func (c *Crawler) barPath(dir, ticker string, adjusted bool, partition string, from, to time.Time) string {
+	ts := layout.Series(c.timespanLabel(), !adjusted)
+	name := c.layout().File(ticker, ts, c.PacketSaver.Extension(), from, to, partition)
+	return filepath.Join(c.tickerDir(dir, ticker), name)
+}
+
+// loggedWriter logs the outcome of a bar file once it is committed.
+type loggedWriter struct {
+	saver.BarWriter
+	ticker, path, format string
+	bars                 int
+}
+
+func (w *loggedWriter) Append(bars []model.Bar) error {
+	w.bars += len(bars)
+	return w.BarWriter.Append(bars)
+}
+
+func (w *loggedWriter) Commit() error {
+	if err := w.BarWriter.Commit(); err != nil {
+		return fmt.Errorf("write %s: %w", w.path, err)
+	}
+	slog.Info("save ok", "ticker", w.ticker, "format", w.format, "path", w.path, "bars", w.bars)
+	return nil
+}
+
+// pathLocks holds one mutex per file path in use; the zero value is ready.
+type pathLocks struct {
+	mu sync.Mutex
+	m  map[string]*pathLock
+}
+
+type pathLock struct {
+	sync.Mutex
+	users int
+}
+
+// lock locks path and returns its unlock function.
+func (l *pathLocks) lock(path string) (unlock func()) {
+	l.mu.Lock()
+	if l.m == nil {
+		l.m = make(map[string]*pathLock)
 	}
-	ext := c.PacketSaver.Extension()
-	ts := c.timespanLabel()
-	var name string
-	if c.SavePerDay {
-		name = fmt.Sprintf("%s_%s_%s.%s", ticker, ts, from.Format("2006-01-02"), ext)
-	} else {
-		name = fmt.Sprintf("%s_%s_%s_to_%s.%s", ticker, ts, from.Format("2006-01-02"), to.Format("2006-01-02"), ext)
+	pl := l.m[path]
+	if pl == nil {
+		pl = &pathLock{}
+		l.m[path] = pl
 	}
-	packetPath := filepath.Join(tickerDir, name)
-	if err := c.PacketSaver.Save(bars, packetPath); err != nil {
-		slog.Error("save: write failed", "ticker", ticker, "path", packetPath, "err", err)
-	} else {
-		slog.Info("save ok", "ticker", ticker, "format", ext, "path", packetPath, "bars", len(bars))
+	pl.users++
+	l.mu.Unlock()
+
+	pl.Lock()
+	return func() {
+		pl.Unlock()
+		l.mu.Lock()
+		if pl.users--; pl.users == 0 {
+			delete(l.m, path)
+		}
+		l.mu.Unlock()
 	}
 }
 
-// splitDateRangeIntoChunks splits [from, to] into day chunks so each request stays under ~maxLimit bars
+// discardWriter is the BarWriter of a Crawler that persists nothing.
+type discardWriter struct{}
+
+func (discardWriter) Append([]model.Bar) error { return nil }
+func (discardWriter) Commit() error            { return nil }
+func (discardWriter) Abort() error             { return nil }
+
+// splitDateRangeIntoChunks splits [from, to] into windows of at most maxDays
+// calendar days so each request stays under ~maxLimit bars. Every window but
+// the last ends at the end of its last day, so consecutive windows leave no
+// gap between them.
 func splitDateRangeIntoChunks(from, to time.Time, maxDays int) [][2]time.Time {
 	var chunks [][2]time.Time
 	start := from.UTC()
 	end := to.UTC()
 
-	if !start.Before(end) && !start.Equal(end) {
+	if start.After(end) {
 		return chunks
 	}
 
 	for currentStart := start; !currentStart.After(end); {
-		currentEnd := currentStart.AddDate(0, 0, maxDays-1)
+		currentEnd := dayStart(currentStart).AddDate(0, 0, maxDays).Add(-time.Millisecond)
 		if currentEnd.After(end) {
 			currentEnd = end
 		}
@@ -206,12 +396,33 @@ func splitDateRangeIntoChunks(from, to time.Time, maxDays int) [][2]time.Time {
 			break
 		}
 
-		currentStart = currentEnd.AddDate(0, 0, 1)
+		currentStart = dayStart(currentEnd).AddDate(0, 0, 1)
 	}
 
 	return chunks
 }
 
+// dayStart truncates t to 00:00 UTC of its date.
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// tradingChunks trims each chunk to the sessions of cal it overlaps and drops
+// chunks without one, so weekends and holidays never cost a request. A nil
+// cal keeps every chunk.
+func tradingChunks(cal calendar.Calendar, chunks [][2]time.Time) [][2]time.Time {
+	if cal == nil {
+		return chunks
+	}
+	out := chunks[:0]
+	for _, ch := range chunks {
+		if from, to, ok := calendar.Trim(cal, ch[0], ch[1]); ok {
+			out = append(out, [2]time.Time{from, to})
+		}
+	}
+	return out
+}
+
 // adjustLastChunkToAvoidDelayed returns chunkTo unchanged, or end of previous day if chunkTo is today/future (avoids DELAYED).
 func adjustLastChunkToAvoidDelayed(chunkTo time.Time, isLastChunk bool) time.Time {
 	if !isLastChunk {
@@ -226,25 +437,23 @@ func adjustLastChunkToAvoidDelayed(chunkTo time.Time, isLastChunk bool) time.Tim
 	return chunkTo
 }
 
-const maxRetries = 3
-const retryDelay = 15 * time.Second
-
 // buildAggregatesRequest builds a GET request for bar aggregates using the
 // configured Timespan and Multiplier (e.g. range/1/minute, range/5/minute, range/1/day).
-func (c *Crawler) buildAggregatesRequest(ticker string, fromMillis, toMillis int64, apiKey string) (*http.Request, error) {
+// adjusted=false requests raw prices, not adjusted for splits.
+func (c *Crawler) buildAggregatesRequest(ctx context.Context, ticker string, fromMillis, toMillis int64, adjusted bool, apiKey string) (*http.Request, error) {
 	rawURL := fmt.Sprintf("%s/v2/aggs/ticker/%s/range/%d/%s/%d/%d",
-		polygonBaseURL, ticker, c.multiplier(), c.timespan(), fromMillis, toMillis)
+		c.baseURL(), ticker, c.multiplier(), c.timespan(), fromMillis, toMillis)
 	u, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, fmt.Errorf("parse URL: %w", err)
 	}
 	q := u.Query()
-	q.Set("adjusted", "true")
+	q.Set("adjusted", strconv.FormatBool(adjusted))
 	q.Set("limit", strconv.Itoa(maxLimit))
 	q.Set("sort", "asc")
 	q.Set("apiKey", apiKey)
 	u.RawQuery = q.Encode()
-	req, err := http.NewRequest("GET", u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -252,68 +461,112 @@ func (c *Crawler) buildAggregatesRequest(ticker string, fromMillis, toMillis int
 	return req, nil
 }
 
-// doAggregatesRequest runs one GET request with retries. On 429 calls on429 before retry.
-// Returns (nil, nil) when status is DELAYED (caller should skip chunk); (nil, err) on error; (resp, nil) on success.
-func (c *Crawler) doAggregatesRequest(client *http.Client, req *http.Request, on429 func()) (*AggregatesResponse, error) {
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		resp, err := client.Do(req)
-		if err != nil {
-			if attempt < maxRetries {
-				time.Sleep(retryDelay)
-				continue
+// doAggregatesRequest runs one GET request with retries per c.Retry.
+// Returns (nil, err) on error and (resp, nil) on success. A DELAYED response is
+// an ErrDelayed error and is not retried here: it will not clear within the
+// request backoff, so the caller reports the range as uncovered instead.
+// Every attempt (including retries) first waits on apiKey's rate-limit bucket.
+// Failures are *APIError values (see errors.go); network errors are wrapped as-is.
+// Only retryable failures are retried, honoring Retry-After. Sleeps abort as
+// soon as ctx is cancelled.
+func (c *Crawler) doAggregatesRequest(ctx context.Context, client *http.Client, req *http.Request, apiKey string) (*AggregatesResponse, error) {
+	policy := c.retryPolicy()
+	var lastErr error
+	for attempt := 1; attempt <= policy.attempts(); attempt++ {
+		if attempt > 1 {
+			d := policy.delay(attempt, lastErr)
+			slog.Debug("request retry",
+				"attempt", attempt, "of", policy.attempts(), "wait", d.Round(time.Millisecond), "err", lastErr)
+			if err := ctxutil.Sleep(ctx, d); err != nil {
+				return nil, err
 			}
-			return nil, fmt.Errorf("API call failed after %d attempts: %w", maxRetries, err)
 		}
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == 429 {
-				if attempt < maxRetries {
-					time.Sleep(retryDelay)
-					if on429 != nil {
-						on429()
-					}
-					continue
-				}
-				return nil, fmt.Errorf("API rate limit (429) after %d attempts: %s", maxRetries, string(body))
-			}
-			return nil, fmt.Errorf("API status %d: %s", resp.StatusCode, string(body))
+		if err := c.waitForKey(ctx, apiKey); err != nil {
+			return nil, err
 		}
 
-		var result AggregatesResponse
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			resp.Body.Close()
-			if attempt < maxRetries {
-				time.Sleep(retryDelay)
-				continue
-			}
-			return nil, fmt.Errorf("parse JSON: %w", err)
+		result, err := c.aggregatesAttempt(client, req)
+		if err == nil {
+			return result, nil
 		}
-		resp.Body.Close()
-
-		if result.Status != "OK" {
-			if result.Status == "DELAYED" {
-				return nil, nil // caller skips chunk
-			}
-			return nil, fmt.Errorf("API status not OK: %s", result.Status)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
+		lastErr = err
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && (!apiErr.Retryable() || apiErr.Kind == ErrDelayed) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("after %d attempts: %w", policy.attempts(), lastErr)
+}
+
+// aggregatesAttempt performs a single aggregates request and classifies the outcome.
+func (c *Crawler) aggregatesAttempt(client *http.Client, req *http.Request) (*AggregatesResponse, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API call: %w", redactKey(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newStatusError(resp, body)
+	}
+
+	var result AggregatesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &APIError{Kind: ErrDecode, RequestID: resp.Header.Get("X-Request-Id"), Err: err}
+	}
+	switch result.Status {
+	case "OK":
 		return &result, nil
+	case "DELAYED":
+		return nil, &APIError{
+			Kind: ErrDelayed, StatusCode: resp.StatusCode,
+			RequestID: result.RequestID, Message: "API status DELAYED",
+		}
+	default:
+		return nil, &APIError{
+			Kind: ErrServerError, StatusCode: resp.StatusCode,
+			RequestID: result.RequestID, Message: "API status " + result.Status,
+		}
 	}
-	return nil, fmt.Errorf("no response")
 }
 
 // CrawlBarsWithKey fetches bar aggregates for the given ticker and time range using
 // the provided API key. The timeframe is determined by Crawler.Timespan and Crawler.Multiplier.
-// Callers are responsible for API-key rotation and rate limiting.
-func (c *Crawler) CrawlBarsWithKey(ticker, apiKey string, from, to time.Time) ([]model.Bar, error) {
+// Callers are responsible for API-key rotation; rate limiting is enforced per
+// key by c.RateLimiter before every request.
+// Cancelling ctx aborts in-flight requests and rate-limit waits; the returned error is ctx.Err().
+//
+// When onChunk is non-nil, each chunk's bars are handed to it as soon as the
+// request completes (in range order) and are not accumulated: the returned
+// slice is nil. An error from onChunk aborts the crawl. This lets the caller
+// checkpoint long backfills chunk by chunk.
+//
+// A chunk that comes back DELAYED ends the crawl with an *UncoveredError for
+// the rest of the range: later chunks are newer still, and delivering them
+// past the hole would let the caller record progress over missing data.
+//
+// adjusted selects split-adjusted (true) or raw (false) prices. Chunks are
+// trimmed to the sessions of cal (nil: no trimming), the trading calendar of
+// the ticker's asset class.
+func (c *Crawler) CrawlBarsWithKey(ctx context.Context, ticker, apiKey string, adjusted bool, cal calendar.Calendar, from, to time.Time, onChunk func(from, to time.Time, bars []model.Bar) error) ([]model.Bar, error) {
 	client := c.client
 	if client == nil {
 		client = http.DefaultClient
 	}
 
-	allBars := make([]model.Bar, 0, c.estimatedBars(from, to))
-	chunks := splitDateRangeIntoChunks(from, to, c.maxDaysPerChunk())
+	// Without onChunk the whole range is returned at once: meant for ranges of
+	// one or a few chunks (chunk jobs). Longer ranges go through onChunk so
+	// only one response is held at a time (see OpenBars for streaming them
+	// into a single file).
+	var allBars []model.Bar
+	if onChunk == nil {
+		allBars = make([]model.Bar, 0, min(c.estimatedBars(from, to), maxLimit))
+	}
+	chunks := tradingChunks(cal, splitDateRangeIntoChunks(from, to, c.MaxDaysPerChunk()))
 	if len(chunks) == 0 {
 		slog.Debug("no chunks in date range",
 			"ticker", ticker, "from", from.Format("2006-01-02"), "to", to.Format("2006-01-02"))
@@ -328,38 +581,37 @@ func (c *Crawler) CrawlBarsWithKey(ticker, apiKey string, from, to time.Time) ([
 		"ticker", ticker, "chunks", len(chunks), "timespan", c.timespanLabel(), "key", keyPfx)
 
 	for chunkIndex, ch := range chunks {
-		if chunkIndex > 0 {
-			slog.Debug("rate cooldown",
-				"ticker", ticker, "chunk", chunkIndex+1, "of", len(chunks),
-				"wait_s", KeyCooldownSec, "key", keyPfx)
-			time.Sleep(KeyCooldownSec * time.Second)
-		}
-
 		chunkFrom := ch[0]
 		chunkTo := adjustLastChunkToAvoidDelayed(ch[1], chunkIndex == len(chunks)-1)
 
-		req, err := c.buildAggregatesRequest(ticker, chunkFrom.UnixMilli(), chunkTo.UnixMilli(), apiKey)
+		req, err := c.buildAggregatesRequest(ctx, ticker, chunkFrom.UnixMilli(), chunkTo.UnixMilli(), adjusted, apiKey)
 		if err != nil {
 			return nil, err
 		}
-		response, err := c.doAggregatesRequest(client, req, nil)
+		response, err := c.doAggregatesRequest(ctx, client, req, apiKey)
+		if errors.Is(err, ErrDelayed) {
+			slog.Debug("chunk delayed, rest of range left uncovered",
+				"ticker", ticker, "from", chunkFrom.Format("2006-01-02"), "to", to.Format("2006-01-02"))
+			return nil, &UncoveredError{From: chunkFrom, To: to, Err: err}
+		}
 		if err != nil {
 			return nil, err
 		}
-		if response == nil {
-			continue // DELAYED
+
+		if onChunk != nil {
+			chunkBars := make([]model.Bar, 0, len(response.Results))
+			for _, barRaw := range response.Results {
+				chunkBars = append(chunkBars, barRaw.ToBar())
+			}
+			if err := onChunk(chunkFrom, chunkTo, chunkBars); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
 		for _, barRaw := range response.Results {
 			allBars = append(allBars, barRaw.ToBar())
 		}
-
-		// Post-last-chunk cooldown: key must be rested before the caller returns it to the pool.
-		if chunkIndex == len(chunks)-1 {
-			slog.Debug("rate cooldown (post-fetch)",
-				"ticker", ticker, "wait_s", KeyCooldownSec, "key", keyPfx)
-			time.Sleep(KeyCooldownSec * time.Second)
-		}
 	}
 	return allBars, nil
 }
@@ -0,0 +1,42 @@
+package polygon
+
+import (
+	"testing"
+	"time"
+
+	"us-data/internal/calendar"
+)
+
+func TestSplitDateRangeIntoChunks(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 10, 23, 59, 59, 999e6, time.UTC)
+	chunks := splitDateRangeIntoChunks(from, to, 4)
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %v, want 3", chunks)
+	}
+	if !chunks[0][0].Equal(from) || !chunks[2][1].Equal(to) {
+		t.Errorf("chunks span %v..%v, want %v..%v", chunks[0][0], chunks[2][1], from, to)
+	}
+	for i := 1; i < len(chunks); i++ {
+		if !chunks[i-1][1].Add(time.Millisecond).Equal(chunks[i][0]) {
+			t.Errorf("gap between chunk %d (ends %v) and %d (starts %v)", i-1, chunks[i-1][1], i, chunks[i][0])
+		}
+	}
+}
+
+func TestTradingChunksKeepFridayPostMarket(t *testing.T) {
+	// Thu 2024-01-04 .. Sun 2024-01-07 in 2-day chunks: the Sat..Sun chunk
+	// keeps Friday's post-market hour (until 01:00 UTC Saturday).
+	from := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 7, 23, 59, 59, 999e6, time.UTC)
+	chunks := tradingChunks(calendar.NYSE, splitDateRangeIntoChunks(from, to, 2))
+	if len(chunks) != 2 {
+		t.Fatalf("chunks = %v, want 2", chunks)
+	}
+	if want := time.Date(2024, 1, 6, 0, 59, 59, 999e6, time.UTC); !chunks[1][1].Equal(want) {
+		t.Errorf("weekend chunk ends at %v, want %v", chunks[1][1], want)
+	}
+	if n := len(tradingChunks(nil, splitDateRangeIntoChunks(from, to, 2))); n != 2 {
+		t.Errorf("nil calendar kept %d chunks, want 2", n)
+	}
+}
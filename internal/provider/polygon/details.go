@@ -0,0 +1,190 @@
+package polygon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"us-data/internal/fsutil"
+)
+
+// TickerDetails is the subset of GET /v3/reference/tickers/{ticker} the
+// crawler needs: whether the ticker trades and over which lifetime.
+type TickerDetails struct {
+	Ticker      string    `json:"ticker"`
+	Active      bool      `json:"active"`
+	NotFound    bool      `json:"not_found,omitempty"`    // 404: unknown symbol
+	ListDate    string    `json:"list_date,omitempty"`    // YYYY-MM-DD; empty when Polygon has none (crypto, fx)
+	DelistedUTC string    `json:"delisted_utc,omitempty"` // RFC 3339; empty while listed
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Listed returns the list date, or the zero time when unknown.
+func (d TickerDetails) Listed() time.Time {
+	t, err := time.ParseInLocation("2006-01-02", d.ListDate, time.UTC)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Delisted returns the delisting time, or the zero time while listed.
+func (d TickerDetails) Delisted() time.Time {
+	t, err := time.Parse(time.RFC3339, d.DelistedUTC)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+// tickerDetailResponse is the reference API envelope.
+type tickerDetailResponse struct {
+	Status  string `json:"status"`
+	Results struct {
+		Ticker      string `json:"ticker"`
+		Active      bool   `json:"active"`
+		ListDate    string `json:"list_date"`
+		DelistedUTC string `json:"delisted_utc"`
+	} `json:"results"`
+}
+
+// DefaultTickerDetailsTTL is how long cached details are trusted before they
+// are fetched again (a listed ticker may be delisted in the meantime).
+const DefaultTickerDetailsTTL = 7 * 24 * time.Hour
+
+// TickerDetailsCache keeps ticker details in a JSON file (e.g. .tickers.json)
+// so each ticker costs one reference request per TTL, not one per cycle.
+// Safe for concurrent use; every new entry is written through atomically.
+type TickerDetailsCache struct {
+	path string
+	ttl  time.Duration
+
+	mu sync.Mutex
+	m  map[string]TickerDetails // nil until first use
+}
+
+// NewTickerDetailsCache returns a cache backed by the JSON file at path.
+// ttl <= 0 uses DefaultTickerDetailsTTL.
+func NewTickerDetailsCache(path string, ttl time.Duration) *TickerDetailsCache {
+	if ttl <= 0 {
+		ttl = DefaultTickerDetailsTTL
+	}
+	return &TickerDetailsCache{path: path, ttl: ttl}
+}
+
+// lookup returns the cached details for ticker if they are still fresh.
+func (c *TickerDetailsCache) lookup(ticker string, now time.Time) (TickerDetails, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ensureLoaded()
+	d, ok := c.m[ticker]
+	if !ok || now.Sub(d.FetchedAt) > c.ttl {
+		return TickerDetails{}, false
+	}
+	return d, true
+}
+
+// store records d and rewrites the file. A failed write only costs a refetch.
+func (c *TickerDetailsCache) store(d TickerDetails) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ensureLoaded()
+	c.m[d.Ticker] = d
+	data, err := json.MarshalIndent(c.m, "", "  ")
+	if err == nil {
+		err = fsutil.WriteFileAtomic(c.path, data, 0o644)
+	}
+	if err != nil {
+		slog.Warn("ticker details cache write failed", "path", c.path, "err", err)
+	}
+}
+
+// ensureLoaded reads the file once; a missing or corrupt file starts empty
+// (the cache is rebuilt from the API, nothing is lost).
+func (c *TickerDetailsCache) ensureLoaded() {
+	if c.m != nil {
+		return
+	}
+	c.m = make(map[string]TickerDetails)
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &c.m)
+	}
+	if err != nil {
+		slog.Warn("ticker details cache unreadable, starting empty", "path", c.path, "err", err)
+		c.m = make(map[string]TickerDetails)
+	}
+}
+
+// GetTickerDetails returns the reference details of ticker, from c.Details
+// when fresh, otherwise from GET /v3/reference/tickers/{ticker} (rate
+// limited on apiKey). An unknown ticker is not an error: it yields
+// NotFound=true, and is cached like any other answer.
+func (c *Crawler) GetTickerDetails(ctx context.Context, apiKey, ticker string) (TickerDetails, error) {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	if c.Details != nil {
+		if d, ok := c.Details.lookup(ticker, time.Now()); ok {
+			return d, nil
+		}
+	}
+	d, err := c.fetchTickerDetails(ctx, refHTTPClient(), apiKey, ticker)
+	if err != nil {
+		return TickerDetails{}, err
+	}
+	if c.Details != nil {
+		c.Details.store(d)
+	}
+	return d, nil
+}
+
+func (c *Crawler) fetchTickerDetails(ctx context.Context, client *http.Client, apiKey, ticker string) (TickerDetails, error) {
+	if err := c.waitForKey(ctx, apiKey); err != nil {
+		return TickerDetails{}, err
+	}
+	u := fmt.Sprintf("%s/v3/reference/tickers/%s?apiKey=%s",
+		c.baseURL(), url.PathEscape(ticker), apiKey)
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return TickerDetails{}, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return TickerDetails{}, fmt.Errorf("ticker details %s: %w", ticker, redactKey(err))
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	d := TickerDetails{Ticker: ticker, FetchedAt: time.Now().UTC()}
+	if resp.StatusCode == http.StatusNotFound {
+		d.NotFound = true
+		return d, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return TickerDetails{}, newStatusError(resp, body)
+	}
+	var detail tickerDetailResponse
+	if err := json.Unmarshal(body, &detail); err != nil {
+		return TickerDetails{}, &APIError{Kind: ErrDecode, StatusCode: resp.StatusCode, Err: err}
+	}
+	if detail.Status != "OK" {
+		d.NotFound = true
+		return d, nil
+	}
+	d.Active = detail.Results.Active
+	d.ListDate = detail.Results.ListDate
+	d.DelistedUTC = detail.Results.DelistedUTC
+	return d, nil
+}
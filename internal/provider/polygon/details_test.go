@@ -0,0 +1,30 @@
+package polygon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTickerDetailsCachePersistsAndExpires(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".tickers.json")
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	c := NewTickerDetailsCache(path, 24*time.Hour)
+	c.store(TickerDetails{Ticker: "ARM", Active: true, ListDate: "2023-09-14", FetchedAt: now})
+
+	// A fresh cache instance reads the entry back from disk.
+	d, ok := NewTickerDetailsCache(path, 24*time.Hour).lookup("ARM", now.Add(time.Hour))
+	if !ok {
+		t.Fatal("entry not persisted")
+	}
+	if want := time.Date(2023, 9, 14, 0, 0, 0, 0, time.UTC); !d.Listed().Equal(want) {
+		t.Errorf("Listed() = %v, want %v", d.Listed(), want)
+	}
+	if !d.Delisted().IsZero() {
+		t.Errorf("Delisted() = %v, want zero for a listed ticker", d.Delisted())
+	}
+	if _, ok := c.lookup("ARM", now.Add(25*time.Hour)); ok {
+		t.Error("stale entry returned after TTL")
+	}
+}
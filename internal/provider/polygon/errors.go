@@ -0,0 +1,257 @@
+package polygon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel error kinds returned (wrapped in *APIError) by Polygon requests.
+// Match with errors.Is; use errors.As to get the *APIError details.
+var (
+	ErrRateLimited  = errors.New("rate limited (429)")
+	ErrUnauthorized = errors.New("unauthorized (invalid API key)")
+	ErrNotFound     = errors.New("not found")
+	ErrBadRequest   = errors.New("bad request")
+	ErrServerError  = errors.New("server error")
+	ErrDecode       = errors.New("decode response")
+	ErrDelayed      = errors.New("data delayed") // status DELAYED: not published yet for this plan
+)
+
+// APIError is a failed Polygon request. Kind is one of the sentinels above
+// (or ErrNotAuthorized for 403) and is matched by errors.Is.
+type APIError struct {
+	Kind       error
+	StatusCode int           // HTTP status; 0 when the failure is not HTTP-level (e.g. decode)
+	RequestID  string        // Polygon request_id, when the response carried one
+	RetryAfter time.Duration // parsed Retry-After header; 0 if absent
+	Message    string        // response body or API status text, truncated
+	Err        error         // underlying cause (decode error), if any
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Kind.Error())
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, ": status %d", e.StatusCode)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request_id=%s)", e.RequestID)
+	}
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	}
+	if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap exposes both the kind sentinel and the underlying cause.
+func (e *APIError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// Retryable reports whether the same request may succeed later.
+// Satisfies crawl.Retryable so the runner can classify failures.
+func (e *APIError) Retryable() bool {
+	switch e.Kind {
+	case ErrRateLimited, ErrServerError, ErrDecode, ErrDelayed:
+		return true
+	}
+	return false
+}
+
+// ErrorKind returns a short machine-readable name for reports and logs.
+func (e *APIError) ErrorKind() string {
+	switch e.Kind {
+	case ErrRateLimited:
+		return "rate_limited"
+	case ErrUnauthorized, ErrNotAuthorized:
+		return "unauthorized"
+	case ErrNotFound:
+		return "not_found"
+	case ErrBadRequest:
+		return "bad_request"
+	case ErrServerError:
+		return "server_error"
+	case ErrDecode:
+		return "decode_error"
+	case ErrDelayed:
+		return "delayed"
+	}
+	return "unknown"
+}
+
+// UncoveredError reports the tail of a requested range that was not fetched:
+// everything before From was delivered, From..To was not (e.g. a chunk came
+// back DELAYED). It satisfies crawl.Uncovered so the runner retries exactly
+// that range instead of recording it as done.
+type UncoveredError struct {
+	From time.Time
+	To   time.Time
+	Err  error
+}
+
+func (e *UncoveredError) Error() string {
+	return fmt.Sprintf("%s..%s not fetched: %v",
+		e.From.Format("2006-01-02"), e.To.Format("2006-01-02"), e.Err)
+}
+
+func (e *UncoveredError) Unwrap() error { return e.Err }
+
+// UncoveredRange returns the range that still has to be fetched.
+func (e *UncoveredError) UncoveredRange() (from, to time.Time) { return e.From, e.To }
+
+// maxErrorMessage bounds the response body kept in APIError.Message.
+const maxErrorMessage = 512
+
+// newStatusError builds an APIError from a non-200 response and its body.
+func newStatusError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  requestID(resp, body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Message:    truncate(strings.TrimSpace(string(body)), maxErrorMessage),
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+	case resp.StatusCode == http.StatusUnauthorized:
+		e.Kind = ErrUnauthorized
+	case resp.StatusCode == http.StatusForbidden:
+		e.Kind = ErrNotAuthorized
+	case resp.StatusCode == http.StatusNotFound:
+		e.Kind = ErrNotFound
+	case resp.StatusCode >= 500:
+		e.Kind = ErrServerError
+	default:
+		e.Kind = ErrBadRequest
+	}
+	return e
+}
+
+// requestID prefers the X-Request-Id header and falls back to the JSON body.
+func requestID(resp *http.Response, body []byte) string {
+	if id := resp.Header.Get("X-Request-Id"); id != "" {
+		return id
+	}
+	var v struct {
+		RequestID string `json:"request_id"`
+	}
+	if json.Unmarshal(body, &v) == nil {
+		return v.RequestID
+	}
+	return ""
+}
+
+// parseRetryAfter accepts both forms allowed by RFC 9110: delay-seconds and HTTP-date.
+func parseRetryAfter(h string, now time.Time) time.Duration {
+	h = strings.TrimSpace(h)
+	if h == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(h); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// redactKey hides the apiKey query parameter in the URL of a transport error
+// (*url.Error prints the whole request URL), so the key never reaches job
+// results, reports or logs. It returns err.
+func redactKey(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		ue.URL = redactURL(ue.URL)
+	}
+	return err
+}
+
+// redactURL returns rawURL with its apiKey query parameter masked.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		base, _, _ := strings.Cut(rawURL, "?")
+		return base
+	}
+	q := u.Query()
+	if !q.Has("apiKey") {
+		return rawURL
+	}
+	q.Set("apiKey", "REDACTED")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
+
+// RetryPolicy controls per-request retries inside doAggregatesRequest.
+//
+// Attempt n (n >= 2) waits BaseDelay × 2^(n-2), capped at MaxDelay, with
+// ±Jitter fraction of random spread. A Retry-After header overrides the
+// computed delay when it asks for longer.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64 // 0..1
+}
+
+// DefaultRetryPolicy matches the historical behaviour (3 attempts, 15s apart)
+// with exponential growth and jitter added.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   15 * time.Second,
+	MaxDelay:    2 * time.Minute,
+	Jitter:      0.2,
+}
+
+// delay returns how long to wait before attempt (2-based) given the error
+// that failed the previous attempt.
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	d := p.BaseDelay
+	for i := 2; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		spread := float64(d) * p.Jitter
+		d += time.Duration((rand.Float64()*2 - 1) * spread)
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > d {
+		d = apiErr.RetryAfter
+	}
+	return d
+}
+
+func (p RetryPolicy) attempts() int {
+	return max(p.MaxAttempts, 1)
+}
@@ -0,0 +1,96 @@
+package polygon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewStatusError(t *testing.T) {
+	cases := []struct {
+		status    int
+		kind      error
+		retryable bool
+	}{
+		{http.StatusTooManyRequests, ErrRateLimited, true},
+		{http.StatusUnauthorized, ErrUnauthorized, false},
+		{http.StatusForbidden, ErrNotAuthorized, false},
+		{http.StatusNotFound, ErrNotFound, false},
+		{http.StatusBadRequest, ErrBadRequest, false},
+		{http.StatusBadGateway, ErrServerError, true},
+	}
+	for _, c := range cases {
+		resp := &http.Response{StatusCode: c.status, Header: http.Header{"Retry-After": {"30"}}}
+		err := newStatusError(resp, []byte(`{"status":"ERROR","request_id":"abc"}`))
+		if !errors.Is(err, c.kind) {
+			t.Errorf("status %d: got kind %v, want %v", c.status, err.Kind, c.kind)
+		}
+		if err.Retryable() != c.retryable {
+			t.Errorf("status %d: retryable = %v, want %v", c.status, err.Retryable(), c.retryable)
+		}
+		if err.RequestID != "abc" || err.RetryAfter != 30*time.Second {
+			t.Errorf("status %d: request_id=%q retry_after=%v", c.status, err.RequestID, err.RetryAfter)
+		}
+	}
+}
+
+func TestRetryPolicyHonorsRetryAfter(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+	if d := p.delay(2, nil); d != time.Second {
+		t.Fatalf("attempt 2: got %v, want 1s", d)
+	}
+	if d := p.delay(5, nil); d != 4*time.Second {
+		t.Fatalf("attempt 5: got %v, want cap 4s", d)
+	}
+	err := &APIError{Kind: ErrRateLimited, RetryAfter: time.Minute}
+	if d := p.delay(2, err); d != time.Minute {
+		t.Fatalf("Retry-After: got %v, want 1m", d)
+	}
+}
+
+func TestDelayedIsNotRetriedPerRequest(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"status":"DELAYED","request_id":"d1","results":[]}`))
+	}))
+	defer srv.Close()
+
+	c := &Crawler{Retry: &RetryPolicy{MaxAttempts: 3}}
+	req, _ := http.NewRequestWithContext(context.Background(), "GET", srv.URL, nil)
+	_, err := c.doAggregatesRequest(context.Background(), srv.Client(), req, "")
+	if !errors.Is(err, ErrDelayed) {
+		t.Fatalf("got %v, want ErrDelayed", err)
+	}
+	if calls != 1 {
+		t.Errorf("DELAYED retried in-request: %d calls", calls)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.Retryable() || apiErr.ErrorKind() != "delayed" {
+		t.Errorf("DELAYED should be a retryable \"delayed\" error for the runner, got %v", err)
+	}
+}
+
+func TestTransportErrorsHideAPIKey(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close() // every request fails in the transport
+	c := &Crawler{BaseURL: srv.URL, Retry: &RetryPolicy{MaxAttempts: 1}}
+	const key = "secret-key"
+
+	req, err := c.buildAggregatesRequest(context.Background(), "AAPL", 0, 1, true, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, aggErr := c.doAggregatesRequest(context.Background(), http.DefaultClient, req, key)
+	_, refErr := c.CrawlActions(context.Background(), "AAPL", key, time.Time{}, time.Now())
+	_, detailsErr := c.GetTickerDetails(context.Background(), key, "AAPL")
+	for name, err := range map[string]error{"aggregates": aggErr, "reference": refErr, "details": detailsErr} {
+		if err == nil || strings.Contains(err.Error(), key) {
+			t.Errorf("%s: err = %v, want a transport error without the key", name, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package polygon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// TickerEvents is the symbol history of a security from
+// GET /vX/reference/tickers/{ticker}/events.
+type TickerEvents struct {
+	CompositeFIGI string
+	Symbols       []SymbolSince // oldest first; the last one is current
+}
+
+// SymbolSince is a symbol of a security and the day it took effect.
+type SymbolSince struct {
+	Ticker string
+	Date   string // YYYY-MM-DD
+}
+
+// Previous returns the symbols the security traded under before it became
+// ticker, most recent first; none when ticker is not among its symbols.
+func (e TickerEvents) Previous(ticker string) []SymbolSince {
+	ticker = strings.ToUpper(ticker)
+	var out []SymbolSince
+	for i := len(e.Symbols) - 1; i >= 0; i-- {
+		if e.Symbols[i].Ticker != ticker {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if s := e.Symbols[j]; s.Ticker != ticker {
+				out = append(out, s)
+			}
+		}
+		break
+	}
+	return out
+}
+
+// Since returns the day the security started trading as ticker, or "".
+func (e TickerEvents) Since(ticker string) string {
+	ticker = strings.ToUpper(ticker)
+	for i := len(e.Symbols) - 1; i >= 0; i-- {
+		if e.Symbols[i].Ticker == ticker {
+			return e.Symbols[i].Date
+		}
+	}
+	return ""
+}
+
+// tickerEventsResponse is the reference API envelope.
+type tickerEventsResponse struct {
+	Results struct {
+		CompositeFIGI string `json:"composite_figi"`
+		Events        []struct {
+			Type         string `json:"type"`
+			Date         string `json:"date"`
+			TickerChange struct {
+				Ticker string `json:"ticker"`
+			} `json:"ticker_change"`
+		} `json:"events"`
+	} `json:"results"`
+}
+
+// GetTickerEvents returns the symbol changes of the security currently
+// trading as ticker (rate limited on apiKey). An unknown ticker, or one the
+// endpoint does not cover (crypto, fx), has no events and is not an error.
+func (c *Crawler) GetTickerEvents(ctx context.Context, apiKey, ticker string) (TickerEvents, error) {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	u := fmt.Sprintf("%s/vX/reference/tickers/%s/events?types=ticker_change",
+		c.baseURL(), url.PathEscape(ticker))
+
+	var page tickerEventsResponse
+	if err := c.fetchReferencePage(ctx, refHTTPClient(), u, apiKey, &page); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return TickerEvents{}, nil
+		}
+		return TickerEvents{}, fmt.Errorf("ticker events %s: %w", ticker, err)
+	}
+	ev := TickerEvents{CompositeFIGI: page.Results.CompositeFIGI}
+	for _, e := range page.Results.Events {
+		if e.Type == "ticker_change" && e.TickerChange.Ticker != "" {
+			ev.Symbols = append(ev.Symbols, SymbolSince{
+				Ticker: strings.ToUpper(e.TickerChange.Ticker), Date: e.Date,
+			})
+		}
+	}
+	sort.SliceStable(ev.Symbols, func(i, j int) bool { return ev.Symbols[i].Date < ev.Symbols[j].Date })
+	return ev, nil
+}
@@ -1,6 +1,7 @@
 package polygon
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -96,7 +97,7 @@ type polygonTickersResponse struct {
 
 // LoadTickersFromPolygon fetches all active tickers for one or more markets
 // from the Massive/Polygon reference API, paginating via next_url.
-func LoadTickersFromPolygon(apiKey string, markets []string) ([]string, error) {
+func (c *Crawler) LoadTickersFromPolygon(ctx context.Context, apiKey string, markets []string) ([]string, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key required")
 	}
@@ -115,10 +116,10 @@ func LoadTickersFromPolygon(apiKey string, markets []string) ([]string, error) {
 		}
 		pageURL := fmt.Sprintf(
 			"%s/v3/reference/tickers?market=%s&active=true&limit=1000&order=asc",
-			polygonBaseURL, url.QueryEscape(market),
+			c.baseURL(), url.QueryEscape(market),
 		)
 		for pageURL != "" {
-			results, next, err := fetchTickerPage(client, pageURL, apiKey)
+			results, next, err := c.fetchTickerPage(ctx, client, pageURL, apiKey)
 			if err != nil {
 				return nil, fmt.Errorf("market %q: %w", market, err)
 			}
@@ -155,7 +156,7 @@ type etfConstituentResponse struct {
 //  1. Try a free public source (GitHub CSV / Wikipedia).
 //  2. If the free source is unavailable for this group, fall back to the
 //     Massive/Polygon ETF API (requires Starter+ plan).
-func LoadTickersForGroup(apiKey, group string) ([]string, error) {
+func (c *Crawler) LoadTickersForGroup(ctx context.Context, apiKey, group string) ([]string, error) {
 	group = strings.ToLower(strings.TrimSpace(group))
 
 	if _, ok := knownGroups[group]; !ok {
@@ -163,7 +164,7 @@ func LoadTickersForGroup(apiKey, group string) ([]string, error) {
 	}
 
 	// 1. Try free source first.
-	tickers, found, err := loadGroupFree(group)
+	tickers, found, err := loadGroupFree(ctx, group)
 	if found {
 		if err != nil {
 			slog.Warn("free source failed, falling back to ETF API",
@@ -183,7 +184,7 @@ func LoadTickersForGroup(apiKey, group string) ([]string, error) {
 
 	etfTicker := knownGroups[group]
 	client := refHTTPClient()
-	pageURL := fmt.Sprintf("%s/etf-global/v1/constituents?ticker=%s", polygonBaseURL, etfTicker)
+	pageURL := fmt.Sprintf("%s/etf-global/v1/constituents?ticker=%s", c.baseURL(), etfTicker)
 
 	var all []string
 	seen := make(map[string]struct{})
@@ -199,10 +200,13 @@ func LoadTickersForGroup(apiKey, group string) ([]string, error) {
 		}
 		u.RawQuery = q.Encode()
 
-		req, _ := http.NewRequest("GET", u.String(), nil)
+		if err := c.waitForKey(ctx, apiKey); err != nil {
+			return nil, err
+		}
+		req, _ := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 		resp, err := client.Do(req)
 		if err != nil {
-			return nil, fmt.Errorf("fetch group %q: %w", group, err)
+			return nil, fmt.Errorf("fetch group %q: %w", group, redactKey(err))
 		}
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
@@ -242,17 +246,11 @@ func LoadTickersForGroup(apiKey, group string) ([]string, error) {
 // Ticker validation
 // ---------------------------------------------------------------------------
 
-type tickerDetailResponse struct {
-	Status  string `json:"status"`
-	Results struct {
-		Ticker string `json:"ticker"`
-		Active bool   `json:"active"`
-	} `json:"results"`
-}
-
-// ValidateTickers checks each ticker against GET /v3/reference/tickers/{ticker}.
-// Returns (valid, invalid, error). Runs validations concurrently (max 8).
-func ValidateTickers(apiKey string, tickers []string) (valid, invalid []string, err error) {
+// ValidateTickers checks each ticker against GET /v3/reference/tickers/{ticker}
+// (via GetTickerDetails, so the answers also fill c.Details).
+// Returns (valid, invalid, error). Runs validations concurrently (max 8), but
+// each request still waits on apiKey's rate-limit bucket.
+func (c *Crawler) ValidateTickers(ctx context.Context, apiKey string, tickers []string) (valid, invalid []string, err error) {
 	if apiKey == "" {
 		return nil, nil, fmt.Errorf("API key required for validation")
 	}
@@ -265,7 +263,6 @@ func ValidateTickers(apiKey string, tickers []string) (valid, invalid []string,
 		isValid bool
 	}
 
-	client := refHTTPClient()
 	sem := make(chan struct{}, 8) // max 8 concurrent
 	results := make(chan result, len(tickers))
 	var wg sync.WaitGroup
@@ -278,38 +275,21 @@ func ValidateTickers(apiKey string, tickers []string) (valid, invalid []string,
 			defer wg.Done()
 			defer func() { <-sem }()
 
-			u := fmt.Sprintf("%s/v3/reference/tickers/%s?apiKey=%s",
-				polygonBaseURL, url.PathEscape(t), apiKey)
-			req, _ := http.NewRequest("GET", u, nil)
-			resp, e := client.Do(req)
+			d, e := c.GetTickerDetails(ctx, apiKey, t)
 			if e != nil {
+				slog.Warn("ticker validation failed", "ticker", t, "err", e)
 				results <- result{t, false}
 				return
 			}
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-
-			if resp.StatusCode == http.StatusNotFound {
-				results <- result{t, false}
-				return
-			}
-			if resp.StatusCode != http.StatusOK {
-				slog.Warn("ticker validation unexpected status",
-					"ticker", t, "status", resp.StatusCode, "body", string(body))
-				results <- result{t, false}
-				return
-			}
-			var detail tickerDetailResponse
-			if err := json.Unmarshal(body, &detail); err != nil {
-				results <- result{t, false}
-				return
-			}
-			results <- result{t, detail.Status == "OK" && detail.Results.Active}
+			results <- result{t, !d.NotFound && d.Active}
 		}()
 	}
 
 	wg.Wait()
 	close(results)
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err // unanswered lookups are not invalid tickers
+	}
 
 	for r := range results {
 		if r.isValid {
@@ -342,9 +322,13 @@ type AssetTickerSpec struct {
 	Groups   []string
 	Tickers  []string
 	Validate bool
+
+	// OnGroup, when set, receives the constituents of each named group
+	// (sp500, nasdaq100, …) as loaded; "all" is not a group in this sense.
+	OnGroup func(group string, tickers []string)
 }
 
-func ResolveAssetTickers(apiKey string, spec AssetTickerSpec) ([]string, error) {
+func (c *Crawler) ResolveAssetTickers(ctx context.Context, apiKey string, spec AssetTickerSpec) ([]string, error) {
 	seen := make(map[string]struct{})
 	var all []string
 
@@ -367,7 +351,7 @@ func ResolveAssetTickers(apiKey string, spec AssetTickerSpec) ([]string, error)
 		}
 		if group == "all" {
 			market := classToMarket(spec.Class)
-			tickers, err := LoadTickersFromPolygon(apiKey, []string{market})
+			tickers, err := c.LoadTickersFromPolygon(ctx, apiKey, []string{market})
 			if err != nil {
 				if errors.Is(err, ErrNotAuthorized) {
 					slog.Warn("group \"all\" skipped: plan upgrade required",
@@ -381,7 +365,7 @@ func ResolveAssetTickers(apiKey string, spec AssetTickerSpec) ([]string, error)
 			}
 			continue
 		}
-		tickers, err := LoadTickersForGroup(apiKey, group)
+		tickers, err := c.LoadTickersForGroup(ctx, apiKey, group)
 		if err != nil {
 			if errors.Is(err, ErrNotAuthorized) {
 				slog.Warn("group skipped: plan upgrade required",
@@ -390,6 +374,9 @@ func ResolveAssetTickers(apiKey string, spec AssetTickerSpec) ([]string, error)
 			}
 			return nil, fmt.Errorf("group %q: %w", group, err)
 		}
+		if spec.OnGroup != nil {
+			spec.OnGroup(group, tickers)
+		}
 		for _, t := range tickers {
 			add(t)
 		}
@@ -409,7 +396,7 @@ func ResolveAssetTickers(apiKey string, spec AssetTickerSpec) ([]string, error)
 	// (group-loaded tickers come from the reference API so they're implicitly valid)
 	if spec.Validate && len(spec.Tickers) > 0 {
 		explicit := dedup(spec.Tickers)
-		valid, invalid, err := ValidateTickers(apiKey, explicit)
+		valid, invalid, err := c.ValidateTickers(ctx, apiKey, explicit)
 		if err != nil {
 			return nil, fmt.Errorf("validate tickers for class %q: %w", spec.Class, err)
 		}
@@ -466,7 +453,7 @@ func refHTTPClient() *http.Client {
 	return &http.Client{Timeout: 20 * time.Second}
 }
 
-func fetchTickerPage(client *http.Client, pageURL, apiKey string) (tickers []string, nextURL string, err error) {
+func (c *Crawler) fetchTickerPage(ctx context.Context, client *http.Client, pageURL, apiKey string) (tickers []string, nextURL string, err error) {
 	u, err := url.Parse(pageURL)
 	if err != nil {
 		return nil, "", fmt.Errorf("parse URL: %w", err)
@@ -477,10 +464,13 @@ func fetchTickerPage(client *http.Client, pageURL, apiKey string) (tickers []str
 	}
 	u.RawQuery = q.Encode()
 
-	req, _ := http.NewRequest("GET", u.String(), nil)
+	if err := c.waitForKey(ctx, apiKey); err != nil {
+		return nil, "", err
+	}
+	req, _ := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, "", fmt.Errorf("request: %w", err)
+		return nil, "", fmt.Errorf("request: %w", redactKey(err))
 	}
 	body, _ := io.ReadAll(resp.Body)
 	resp.Body.Close()
@@ -9,6 +9,7 @@ package polygon
 //   - DJI      : Wikipedia wikitext API (section "Components")
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -31,22 +32,22 @@ const (
 )
 
 // freeGroupLoaders maps known group names to their free-source loader.
-var freeGroupLoaders = map[string]func() ([]string, error){
-	"sp500":    loadSP500Free,
+var freeGroupLoaders = map[string]func(context.Context) ([]string, error){
+	"sp500":     loadSP500Free,
 	"nasdaq100": loadNasdaq100Free,
-	"dji":      loadDJIFree,
+	"dji":       loadDJIFree,
 }
 
 // loadGroupFree tries to load constituents from a free public source.
 // Returns (tickers, true, nil) on success.
 // Returns (nil, false, nil) when no free source exists for the group.
 // Returns (nil, true, err) when the free source is found but fails.
-func loadGroupFree(group string) (tickers []string, found bool, err error) {
+func loadGroupFree(ctx context.Context, group string) (tickers []string, found bool, err error) {
 	loader, ok := freeGroupLoaders[group]
 	if !ok {
 		return nil, false, nil
 	}
-	tickers, err = loader()
+	tickers, err = loader(ctx)
 	return tickers, true, err
 }
 
@@ -54,15 +55,19 @@ func loadGroupFree(group string) (tickers []string, found bool, err error) {
 // S&P 500 — GitHub raw CSV
 // ---------------------------------------------------------------------------
 
-func loadSP500Free() ([]string, error) {
+func loadSP500Free(ctx context.Context) ([]string, error) {
 	slog.Debug("fetching S&P 500 from GitHub CSV")
-	return fetchCSVColumn(sp500CSV, "Symbol")
+	return fetchCSVColumn(ctx, sp500CSV, "Symbol")
 }
 
 // fetchCSVColumn downloads a CSV and returns all values in the named column.
-func fetchCSVColumn(url, column string) ([]string, error) {
+func fetchCSVColumn(ctx context.Context, url, column string) ([]string, error) {
 	client := &http.Client{Timeout: 20 * time.Second}
-	resp, err := client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetch %s: %w", url, err)
 	}
@@ -123,27 +128,27 @@ var nasdaq100Re = regexp.MustCompile(`(?m)^\| ([A-Z0-9]+) \|\|`)
 // DJI wikitext: tickers appear as {{NYSE link|MMM}} or {{NASDAQ link|AMGN}}
 var djiRe = regexp.MustCompile(`\{\{(?:NYSE|NASDAQ) link\|([A-Z.]+)\}\}`)
 
-func loadNasdaq100Free() ([]string, error) {
+func loadNasdaq100Free(ctx context.Context) ([]string, error) {
 	slog.Debug("fetching NASDAQ-100 from Wikipedia")
-	wikitext, err := fetchWikitext(wikiAPINasdaq100)
+	wikitext, err := fetchWikitext(ctx, wikiAPINasdaq100)
 	if err != nil {
 		return nil, err
 	}
 	return extractMatches(wikitext, nasdaq100Re, 1)
 }
 
-func loadDJIFree() ([]string, error) {
+func loadDJIFree(ctx context.Context) ([]string, error) {
 	slog.Debug("fetching DJI from Wikipedia")
-	wikitext, err := fetchWikitext(wikiAPIDJI)
+	wikitext, err := fetchWikitext(ctx, wikiAPIDJI)
 	if err != nil {
 		return nil, err
 	}
 	return extractMatches(wikitext, djiRe, 1)
 }
 
-func fetchWikitext(apiURL string) (string, error) {
+func fetchWikitext(ctx context.Context, apiURL string) (string, error) {
 	client := &http.Client{Timeout: 20 * time.Second}
-	req, _ := http.NewRequest("GET", apiURL, nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	req.Header.Set("User-Agent", "us-data-crawler/1.0 (github.com/us-data; contact@example.com)")
 	resp, err := client.Do(req)
 	if err != nil {
@@ -0,0 +1,159 @@
+package polygon
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"us-data/internal/ctxutil"
+)
+
+// FreePlanRequestsPerMinute is the Polygon free-plan budget: 5 req/min per key.
+const FreePlanRequestsPerMinute = 5
+
+// RateLimit is the request budget of one API key.
+//
+// RequestsPerMinute <= 0 means unthrottled (paid plans).
+// Burst is the number of requests that may be issued back-to-back before the
+// refill rate kicks in; values < 1 are treated as 1. Keep Burst at 1 for the
+// free plan: Polygon counts a rolling minute, so any burst above 1 can exceed
+// 5 requests in a 60s window.
+type RateLimit struct {
+	RequestsPerMinute int
+	Burst             int
+}
+
+// Unlimited reports whether the budget disables throttling.
+func (l RateLimit) Unlimited() bool { return l.RequestsPerMinute <= 0 }
+
+// RateLimiter hands out per-key token buckets. Every outgoing request that
+// carries an API key — aggregates, ticker reference, validation — waits on the
+// same bucket for that key, so the budget is shared across call sites.
+//
+// Safe for concurrent use.
+type RateLimiter struct {
+	def     RateLimit
+	prefix  map[string]RateLimit // key-prefix overrides (see NewRateLimiter)
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// NewRateLimiter creates a limiter where every key gets def unless an entry in
+// overrides matches. Overrides are keyed by API-key prefix (the same 8-char
+// prefix shown in logs), so secrets never have to appear in config.yaml.
+func NewRateLimiter(def RateLimit, overrides map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		def:     def,
+		prefix:  overrides,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// limitFor returns the budget configured for apiKey (longest matching prefix wins).
+func (l *RateLimiter) limitFor(apiKey string) RateLimit {
+	best, bestLen := l.def, -1
+	for p, lim := range l.prefix {
+		if p != "" && strings.HasPrefix(apiKey, p) && len(p) > bestLen {
+			best, bestLen = lim, len(p)
+		}
+	}
+	return best
+}
+
+// Wait blocks until apiKey may issue one request or ctx is cancelled.
+// A nil limiter never blocks.
+func (l *RateLimiter) Wait(ctx context.Context, apiKey string) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	b := l.bucket(apiKey)
+	if b == nil {
+		return ctx.Err()
+	}
+	wait := b.reserve(time.Now())
+	if err := ctxutil.Sleep(ctx, wait); err != nil {
+		b.refund()
+		return err
+	}
+	return nil
+}
+
+// bucket returns the token bucket for apiKey, creating it on first use.
+// Returns nil for unthrottled keys.
+func (l *RateLimiter) bucket(apiKey string) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[apiKey]; ok {
+		return b
+	}
+	lim := l.limitFor(apiKey)
+	var b *tokenBucket
+	if !lim.Unlimited() {
+		b = newTokenBucket(lim)
+	}
+	l.buckets[apiKey] = b
+	return b
+}
+
+// tokenBucket is a classic token bucket that allows the balance to go
+// negative: reserve always succeeds and returns how long the caller must wait
+// for its token, so concurrent callers queue in arrival order.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(lim RateLimit) *tokenBucket {
+	burst := lim.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   float64(lim.RequestsPerMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// refund returns a reserved token when the caller gave up before using it.
+func (b *tokenBucket) refund() {
+	b.mu.Lock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.mu.Unlock()
+}
+
+// freePlanLimiter returns a limiter giving every key the free-plan budget,
+// the default of a Crawler (see NewCrawler).
+func freePlanLimiter() *RateLimiter {
+	return NewRateLimiter(RateLimit{RequestsPerMinute: FreePlanRequestsPerMinute, Burst: 1}, nil)
+}
+
+// waitForKey blocks on c's limiter for apiKey. A Crawler without a
+// RateLimiter never waits.
+func (c *Crawler) waitForKey(ctx context.Context, apiKey string) error {
+	return c.RateLimiter.Wait(ctx, apiKey)
+}
@@ -0,0 +1,51 @@
+package polygon
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	b := newTokenBucket(RateLimit{RequestsPerMinute: 5, Burst: 1})
+	now := time.Unix(0, 0)
+
+	if w := b.reserve(now); w != 0 {
+		t.Fatalf("first request should not wait, got %v", w)
+	}
+	if w := b.reserve(now); w != 12*time.Second {
+		t.Fatalf("second request should wait 12s, got %v", w)
+	}
+	// Third request queues behind the second.
+	if w := b.reserve(now); w != 24*time.Second {
+		t.Fatalf("third request should wait 24s, got %v", w)
+	}
+	// After a full refill window the balance is capped at burst.
+	if w := b.reserve(now.Add(10 * time.Minute)); w != 0 {
+		t.Fatalf("request after idle should not wait, got %v", w)
+	}
+}
+
+func TestRateLimiterOverrides(t *testing.T) {
+	l := NewRateLimiter(RateLimit{RequestsPerMinute: 5, Burst: 1}, map[string]RateLimit{
+		"paid": {RequestsPerMinute: 0},
+	})
+	if b := l.bucket("paid-key-123"); b != nil {
+		t.Fatalf("paid key should be unthrottled")
+	}
+	if b := l.bucket("free-key-123"); b == nil {
+		t.Fatalf("free key should get a bucket")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 10; i++ {
+		if err := l.Wait(ctx, "paid-key-123"); err != nil {
+			t.Fatalf("unthrottled wait: %v", err)
+		}
+	}
+	_ = l.Wait(ctx, "free-key-123") // consumes the burst
+	cancel()
+	if err := l.Wait(ctx, "free-key-123"); err == nil {
+		t.Fatalf("expected ctx error once cancelled")
+	}
+}
@@ -25,9 +25,11 @@ func newHTTPClient() *http.Client {
 	}
 }
 
-// NewCrawler constructs a Crawler with a shared HTTP client.
+// NewCrawler constructs a Crawler with a shared HTTP client and the
+// free-plan rate limit; replace RateLimiter for other budgets.
 func NewCrawler() (*Crawler, error) {
 	return &Crawler{
-		client: newHTTPClient(),
+		client:      newHTTPClient(),
+		RateLimiter: freePlanLimiter(),
 	}, nil
 }
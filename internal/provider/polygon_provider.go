@@ -1,8 +1,11 @@
 package provider
 
 import (
+	"context"
 	"time"
 
+	"us-data/internal/calendar"
+	"us-data/internal/crawl"
 	"us-data/internal/model"
 	"us-data/internal/provider/polygon"
 	"us-data/internal/saver"
@@ -36,11 +39,45 @@ func (p *PolygonProvider) Close() error    { return p.Crawler.Close() }
 
 // FetchBars retrieves OHLCV bars for ticker over [from, to] using apiKey.
 // The timeframe (timespan × multiplier) is determined at construction time.
-func (p *PolygonProvider) FetchBars(ticker, apiKey string, from, to time.Time) ([]model.Bar, error) {
-	return p.Crawler.CrawlBarsWithKey(ticker, apiKey, from, to)
+// Cancelling ctx interrupts in-flight requests and rate-limit cooldowns.
+// A non-nil onChunk receives each completed chunk instead of the return value.
+// adj selects split-adjusted (adjusted=true) or raw (adjusted=false) aggregates;
+// requests are trimmed to the sessions of cal.
+func (p *PolygonProvider) FetchBars(ctx context.Context, ticker, apiKey string, adj crawl.Adjustment, cal calendar.Calendar, from, to time.Time, onChunk crawl.ChunkFunc) ([]model.Bar, error) {
+	return p.Crawler.CrawlBarsWithKey(ctx, ticker, apiKey, adj != crawl.Raw, cal, from, to, onChunk)
 }
 
-// SaveBars persists bars to dir/ticker/ using the configured storage format.
-func (p *PolygonProvider) SaveBars(dir, ticker string, from, to time.Time, bars []model.Bar) {
-	p.Crawler.SaveBars(dir, ticker, from, to, bars)
+// SaveBars persists bars to dir/ticker/ using the configured storage format,
+// into the file of their partition period when partitioned.
+func (p *PolygonProvider) SaveBars(dir, ticker string, adj crawl.Adjustment, partition crawl.Partition, from, to time.Time, bars []model.Bar) error {
+	return p.Crawler.SaveBars(dir, ticker, adj != crawl.Raw, string(partition), from, to, bars)
+}
+
+// OpenBars starts a streamed bar file in dir/ticker/ covering [from, to].
+func (p *PolygonProvider) OpenBars(dir, ticker string, adj crawl.Adjustment, from, to time.Time) (crawl.BarWriter, error) {
+	return p.Crawler.OpenBars(dir, ticker, adj != crawl.Raw, from, to)
+}
+
+// FetchActions retrieves the splits and dividends of ticker effective in
+// [from, to]; a zero from fetches the whole history.
+// Implements crawl.ActionFetcher (SaveActions is promoted from the Crawler).
+func (p *PolygonProvider) FetchActions(ctx context.Context, ticker, apiKey string, from, to time.Time) ([]model.CorporateAction, error) {
+	return p.Crawler.CrawlActions(ctx, ticker, apiKey, from, to)
+}
+
+// Splits retrieves the splits of all tickers executed in [from, to].
+// Implements crawl.SplitSource.
+func (p *PolygonProvider) Splits(ctx context.Context, apiKey string, from, to time.Time) ([]model.CorporateAction, error) {
+	return p.Crawler.CrawlSplits(ctx, apiKey, from, to)
+}
+
+// TickerLifetime returns the list and delisting dates of ticker from the
+// reference API (cached in the Crawler's Details).
+// Implements crawl.LifetimeSource.
+func (p *PolygonProvider) TickerLifetime(ctx context.Context, ticker, apiKey string) (crawl.Lifetime, error) {
+	d, err := p.Crawler.GetTickerDetails(ctx, apiKey, ticker)
+	if err != nil {
+		return crawl.Lifetime{}, err
+	}
+	return crawl.Lifetime{Listed: d.Listed(), Delisted: d.Delisted()}, nil
 }
@@ -2,6 +2,9 @@ package saver
 
 import (
 	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"strconv"
 
@@ -13,20 +16,31 @@ type CSVSaver struct{}
 
 func (CSVSaver) Extension() string { return "csv" }
 
-func (CSVSaver) Save(bars []model.Bar, path string) error {
-	f, err := os.Create(path)
+func (s CSVSaver) Save(bars []model.Bar, path string) error {
+	return writeBars(s, bars, path)
+}
+
+// OpenBars streams bars into a CSV file; the header is written at once.
+func (CSVSaver) OpenBars(path string) (BarWriter, error) {
+	fw, err := createFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer f.Close()
-	w := csv.NewWriter(f)
-	defer w.Flush()
-
-	if err := w.Write([]string{"t", "o", "h", "l", "c", "v", "vw", "n"}); err != nil {
-		return err
+	c := &csvBarWriter{fileWriter: fw, w: csv.NewWriter(fw.f)}
+	if err := c.w.Write([]string{"t", "o", "h", "l", "c", "v", "vw", "n"}); err != nil {
+		return nil, errors.Join(err, c.discard())
 	}
+	return c, nil
+}
+
+type csvBarWriter struct {
+	fileWriter
+	w *csv.Writer
+}
+
+func (c *csvBarWriter) Append(bars []model.Bar) error {
 	for _, b := range bars {
-		if err := w.Write([]string{
+		if err := c.w.Write([]string{
 			strconv.FormatInt(b.Timestamp, 10),
 			floatStr(b.Open),
 			floatStr(b.High),
@@ -42,4 +56,148 @@ func (CSVSaver) Save(bars []model.Bar, path string) error {
 	return nil
 }
 
+func (c *csvBarWriter) Commit() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return errors.Join(err, c.discard())
+	}
+	return c.close()
+}
+
+func (c *csvBarWriter) Abort() error {
+	return c.discard()
+}
+
+// LoadBars reads a bar file written by OpenBars; the header is skipped.
+func (CSVSaver) LoadBars(path string) ([]model.Bar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	bars := make([]model.Bar, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		b, err := parseBar(row)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: line %d: %w", path, i+2, err)
+		}
+		bars = append(bars, b)
+	}
+	return bars, nil
+}
+
+// parseBar parses one CSV row in the t,o,h,l,c,v,vw,n order of the header.
+func parseBar(row []string) (model.Bar, error) {
+	var b model.Bar
+	if len(row) != 8 {
+		return b, fmt.Errorf("%d fields, want 8", len(row))
+	}
+	ints := []*int64{&b.Timestamp, &b.Volume, &b.Transactions}
+	for i, col := range []int{0, 5, 7} {
+		v, err := strconv.ParseInt(row[col], 10, 64)
+		if err != nil {
+			return b, err
+		}
+		*ints[i] = v
+	}
+	floats := []*float64{&b.Open, &b.High, &b.Low, &b.Close, &b.VWAP}
+	for i, col := range []int{1, 2, 3, 4, 6} {
+		v, err := strconv.ParseFloat(row[col], 64)
+		if err != nil {
+			return b, err
+		}
+		*floats[i] = v
+	}
+	return b, nil
+}
+
+// actionHeader is the CSV header of SaveActions, in model.CorporateAction order.
+var actionHeader = []string{
+	"id", "type", "ticker", "date", "split_from", "split_to",
+	"cash_amount", "currency", "dividend_type", "frequency",
+	"declaration_date", "record_date", "pay_date",
+}
+
+func (CSVSaver) SaveActions(actions []model.CorporateAction, path string) error {
+	return writeFile(path, func(f io.Writer) error {
+		w := csv.NewWriter(f)
+		if err := w.Write(actionHeader); err != nil {
+			return err
+		}
+		for _, a := range actions {
+			if err := w.Write([]string{
+				a.ID, a.Type, a.Ticker, a.Date,
+				floatStr(a.SplitFrom),
+				floatStr(a.SplitTo),
+				floatStr(a.CashAmount),
+				a.Currency, a.DividendType,
+				strconv.FormatInt(a.Frequency, 10),
+				a.DeclarationDate, a.RecordDate, a.PayDate,
+			}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	})
+}
+
+// LoadActions reads an actions file written by SaveActions; the header is
+// skipped.
+func (CSVSaver) LoadActions(path string) ([]model.CorporateAction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	actions := make([]model.CorporateAction, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		a, err := parseAction(row)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: line %d: %w", path, i+2, err)
+		}
+		actions = append(actions, a)
+	}
+	return actions, nil
+}
+
+// parseAction parses one CSV row in actionHeader order.
+func parseAction(row []string) (model.CorporateAction, error) {
+	var a model.CorporateAction
+	if len(row) != len(actionHeader) {
+		return a, fmt.Errorf("%d fields, want %d", len(row), len(actionHeader))
+	}
+	a.ID, a.Type, a.Ticker, a.Date = row[0], row[1], row[2], row[3]
+	floats := []*float64{&a.SplitFrom, &a.SplitTo, &a.CashAmount}
+	for i, col := range []int{4, 5, 6} {
+		v, err := strconv.ParseFloat(row[col], 64)
+		if err != nil {
+			return a, err
+		}
+		*floats[i] = v
+	}
+	a.Currency, a.DividendType = row[7], row[8]
+	v, err := strconv.ParseInt(row[9], 10, 64)
+	if err != nil {
+		return a, err
+	}
+	a.Frequency = v
+	a.DeclarationDate, a.RecordDate, a.PayDate = row[10], row[11], row[12]
+	return a, nil
+}
+
 func floatStr(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
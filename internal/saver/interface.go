@@ -9,6 +9,15 @@ import (
 // High-level (main) inject implementation; low-level (crawler) chỉ phụ thuộc interface — DIP.
 type PacketSaver interface {
 	Save(bars []model.Bar, path string) error
+	// OpenBars starts a streamed bar file at path (see BarWriter); Save is
+	// the same write in one batch.
+	OpenBars(path string) (BarWriter, error)
+	// LoadBars reads back a bar file written by Save or OpenBars.
+	LoadBars(path string) ([]model.Bar, error)
+	// SaveActions writes corporate actions (splits, dividends) in the same format.
+	SaveActions(actions []model.CorporateAction, path string) error
+	// LoadActions reads back an actions file written by SaveActions.
+	LoadActions(path string) ([]model.CorporateAction, error)
 	Extension() string
 }
 
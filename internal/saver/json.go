@@ -1,7 +1,11 @@
 package saver
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 
 	"us-data/internal/model"
@@ -12,13 +16,94 @@ type JSONSaver struct{}
 
 func (JSONSaver) Extension() string { return "json" }
 
-func (JSONSaver) Save(bars []model.Bar, path string) error {
-	f, err := os.Create(path)
+func (s JSONSaver) Save(bars []model.Bar, path string) error {
+	return writeBars(s, bars, path)
+}
+
+// OpenBars streams bars into an indented JSON array, element by element:
+// the file reads the same as one encoded slice.
+func (JSONSaver) OpenBars(path string) (BarWriter, error) {
+	fw, err := createFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonBarWriter{fileWriter: fw, w: bufio.NewWriter(fw.f)}, nil
+}
+
+func (JSONSaver) LoadBars(path string) ([]model.Bar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var bars []model.Bar
+	if err := json.Unmarshal(data, &bars); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return bars, nil
+}
+
+func (JSONSaver) SaveActions(actions []model.CorporateAction, path string) error {
+	return writeFile(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(actions)
+	})
+}
+
+func (JSONSaver) LoadActions(path string) ([]model.CorporateAction, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var actions []model.CorporateAction
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
 	}
-	defer f.Close()
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "  ")
-	return enc.Encode(bars)
+	return actions, nil
+}
+
+type jsonBarWriter struct {
+	fileWriter
+	w *bufio.Writer
+	n int // elements written
+}
+
+func (j *jsonBarWriter) Append(bars []model.Bar) error {
+	for _, b := range bars {
+		data, err := json.MarshalIndent(b, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n  "
+		if j.n == 0 {
+			sep = "[\n  "
+		}
+		if _, err := j.w.WriteString(sep); err != nil {
+			return err
+		}
+		if _, err := j.w.Write(data); err != nil {
+			return err
+		}
+		j.n++
+	}
+	return nil
+}
+
+func (j *jsonBarWriter) Commit() error {
+	end := "\n]\n"
+	if j.n == 0 {
+		end = "[]\n"
+	}
+	_, err := j.w.WriteString(end)
+	if err == nil {
+		err = j.w.Flush()
+	}
+	if err != nil {
+		return errors.Join(err, j.discard())
+	}
+	return j.close()
+}
+
+func (j *jsonBarWriter) Abort() error {
+	return j.discard()
 }
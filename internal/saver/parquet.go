@@ -1,6 +1,9 @@
 package saver
 
 import (
+	"errors"
+	"io"
+
 	"github.com/parquet-go/parquet-go"
 
 	"us-data/internal/model"
@@ -11,6 +14,56 @@ type ParquetSaver struct{}
 
 func (ParquetSaver) Extension() string { return "parquet" }
 
-func (ParquetSaver) Save(bars []model.Bar, path string) error {
-	return parquet.WriteFile(path, bars)
+func (s ParquetSaver) Save(bars []model.Bar, path string) error {
+	return writeBars(s, bars, path)
+}
+
+// OpenBars streams bars into a Parquet file; every Append becomes one row
+// group, so memory is bounded by the largest batch.
+func (ParquetSaver) OpenBars(path string) (BarWriter, error) {
+	fw, err := createFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetBarWriter{fileWriter: fw, w: parquet.NewGenericWriter[model.Bar](fw.f)}, nil
+}
+
+func (ParquetSaver) LoadBars(path string) ([]model.Bar, error) {
+	return parquet.ReadFile[model.Bar](path)
+}
+
+func (ParquetSaver) SaveActions(actions []model.CorporateAction, path string) error {
+	return writeFile(path, func(w io.Writer) error {
+		return parquet.Write(w, actions)
+	})
+}
+
+func (ParquetSaver) LoadActions(path string) ([]model.CorporateAction, error) {
+	return parquet.ReadFile[model.CorporateAction](path)
+}
+
+type parquetBarWriter struct {
+	fileWriter
+	w *parquet.GenericWriter[model.Bar]
+}
+
+func (p *parquetBarWriter) Append(bars []model.Bar) error {
+	if len(bars) == 0 {
+		return nil
+	}
+	if _, err := p.w.Write(bars); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+func (p *parquetBarWriter) Commit() error {
+	if err := p.w.Close(); err != nil { // writes the footer
+		return errors.Join(err, p.discard())
+	}
+	return p.close()
+}
+
+func (p *parquetBarWriter) Abort() error {
+	return p.discard()
 }
@@ -0,0 +1,104 @@
+package saver
+
+import (
+	"cmp"
+	"errors"
+	"io"
+	"slices"
+
+	"us-data/internal/fsutil"
+	"us-data/internal/model"
+)
+
+// BarWriter streams bars into one file, batch by batch, so a long range never
+// has to be held in memory before it is saved.
+//
+// The file appears at its path only on Commit, complete; Abort discards
+// everything written.
+// Exactly one of them must be called. An error from Append leaves the writer
+// to be aborted.
+type BarWriter interface {
+	Append(bars []model.Bar) error
+	Commit() error
+	Abort() error
+}
+
+// writeBars saves bars to path in one go through ps's BarWriter, so Save and
+// streaming produce the same file.
+func writeBars(ps PacketSaver, bars []model.Bar, path string) error {
+	w, err := ps.OpenBars(path)
+	if err != nil {
+		return err
+	}
+	if err := w.Append(bars); err != nil {
+		return errors.Join(err, w.Abort())
+	}
+	return w.Commit()
+}
+
+// MergeBars merges bars into the bars already saved in a file, ordered by
+// timestamp. A bar of bars replaces a saved bar with the same timestamp.
+func MergeBars(saved, bars []model.Bar) []model.Bar {
+	out := make([]model.Bar, 0, len(saved)+len(bars))
+	out = append(out, bars...)
+	out = append(out, saved...)
+	slices.SortStableFunc(out, func(a, b model.Bar) int { return cmp.Compare(a.Timestamp, b.Timestamp) })
+	return slices.CompactFunc(out, func(a, b model.Bar) bool { return a.Timestamp == b.Timestamp })
+}
+
+// MergeActions merges actions into the actions already saved in a file,
+// ordered by date. An action of actions replaces a saved one with the same ID
+// (or, without IDs, the same type and date).
+func MergeActions(saved, actions []model.CorporateAction) []model.CorporateAction {
+	key := func(a model.CorporateAction) string {
+		if a.ID != "" {
+			return a.ID
+		}
+		return a.Type + "|" + a.Date
+	}
+	seen := make(map[string]bool, len(saved)+len(actions))
+	out := make([]model.CorporateAction, 0, len(saved)+len(actions))
+	for _, a := range slices.Concat(actions, saved) {
+		if k := key(a); !seen[k] {
+			seen[k] = true
+			out = append(out, a)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b model.CorporateAction) int { return cmp.Compare(a.Date, b.Date) })
+	return out
+}
+
+// fileWriter holds the output file of a BarWriter. It is written under a
+// temp name and renamed into place once complete (see fsutil.AtomicFile), so
+// a crash never leaves a truncated file under a bar file name.
+type fileWriter struct {
+	f *fsutil.AtomicFile
+}
+
+func createFile(path string) (fileWriter, error) {
+	f, err := fsutil.CreateAtomic(path, 0o644)
+	return fileWriter{f: f}, err
+}
+
+// close makes the complete file visible at its path.
+func (fw fileWriter) close() error {
+	return fw.f.Commit()
+}
+
+// discard removes the partial file.
+func (fw fileWriter) discard() error {
+	return fw.f.Abort()
+}
+
+// writeFile writes path atomically through write; nothing is left at path
+// when write fails.
+func writeFile(path string, write func(w io.Writer) error) error {
+	fw, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	if err := write(fw.f); err != nil {
+		return errors.Join(err, fw.discard())
+	}
+	return fw.close()
+}
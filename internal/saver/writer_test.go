@@ -0,0 +1,194 @@
+package saver
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+
+	"us-data/internal/model"
+)
+
+func testBars(n int, start int64) []model.Bar {
+	out := make([]model.Bar, n)
+	for i := range out {
+		out[i] = model.Bar{Timestamp: start + int64(i)*60000, Open: 1, High: 2, Low: 0.5, Close: 1.5, Volume: 100, VWAP: 1.2, Transactions: int64(i)}
+	}
+	return out
+}
+
+// stream writes bars to path in batches of batch bars.
+func stream(t *testing.T, ps PacketSaver, path string, bars []model.Bar, batch int) {
+	t.Helper()
+	w, err := ps.OpenBars(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(bars); i += batch {
+		if err := w.Append(bars[i:min(i+batch, len(bars))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamedFilesMatchOneShotEncoding(t *testing.T) {
+	dir := t.TempDir()
+	bars := testBars(7, 1700000000000)
+
+	// JSON: the same bytes as one indented encode of the slice.
+	jsonPath := filepath.Join(dir, "bars.json")
+	stream(t, JSONSaver{}, jsonPath, bars, 3)
+	var want bytes.Buffer
+	enc := json.NewEncoder(&want)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(bars); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(jsonPath); !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("streamed JSON differs:\n%s\nwant:\n%s", got, want.Bytes())
+	}
+
+	// Parquet: one row group per batch, all rows in order.
+	pqPath := filepath.Join(dir, "bars.parquet")
+	stream(t, ParquetSaver{}, pqPath, bars, 3)
+	f, err := os.Open(pqPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	st, _ := f.Stat()
+	pf, err := parquet.OpenFile(f, st.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(pf.RowGroups()); n != 3 {
+		t.Errorf("row groups = %d, want 3", n)
+	}
+	rows, err := parquet.ReadFile[model.Bar](pqPath)
+	if err != nil || len(rows) != len(bars) || rows[6] != bars[6] {
+		t.Errorf("parquet rows = %d, %v; want %d in order", len(rows), err, len(bars))
+	}
+
+	// CSV: header plus one line per bar.
+	csvPath := filepath.Join(dir, "bars.csv")
+	stream(t, CSVSaver{}, csvPath, bars, 3)
+	if got, _ := os.ReadFile(csvPath); bytes.Count(got, []byte("\n")) != len(bars)+1 {
+		t.Errorf("csv lines = %d, want %d", bytes.Count(got, []byte("\n")), len(bars)+1)
+	}
+}
+
+func TestAbortLeavesNoFile(t *testing.T) {
+	for _, ps := range []PacketSaver{CSVSaver{}, JSONSaver{}, ParquetSaver{}} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "bars."+ps.Extension())
+		w, err := ps.OpenBars(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Append(testBars(2, 0)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: file visible before commit (%v)", ps.Extension(), err)
+		}
+		if err := w.Abort(); err != nil {
+			t.Errorf("%s: abort: %v", ps.Extension(), err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("%s: %d files left after abort", ps.Extension(), len(entries))
+		}
+	}
+}
+
+func TestLoadBarsRoundTrip(t *testing.T) {
+	bars := testBars(5, 1_700_000_000_000)
+	bars[2].VWAP, bars[2].Transactions = 0, 0 // omitted optional fields
+	for _, ps := range []PacketSaver{CSVSaver{}, JSONSaver{}, ParquetSaver{}} {
+		path := filepath.Join(t.TempDir(), "bars."+ps.Extension())
+		if err := ps.Save(bars, path); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ps.LoadBars(path)
+		if err != nil {
+			t.Fatalf("%s: LoadBars: %v", ps.Extension(), err)
+		}
+		if len(got) != len(bars) {
+			t.Fatalf("%s: %d bars, want %d", ps.Extension(), len(got), len(bars))
+		}
+		for i := range bars {
+			if got[i] != bars[i] {
+				t.Errorf("%s: bar %d = %+v, want %+v", ps.Extension(), i, got[i], bars[i])
+			}
+		}
+	}
+}
+
+func TestMergeBars(t *testing.T) {
+	saved := testBars(4, 0) // t = 0, 1, 2, 3 min
+	fresh := testBars(3, 2*60000)
+	for i := range fresh {
+		fresh[i].Close = 9 // t = 2, 3, 4 min, refetched
+	}
+	got := MergeBars(saved, fresh)
+	if len(got) != 5 {
+		t.Fatalf("merged %d bars, want 5", len(got))
+	}
+	for i, b := range got {
+		if b.Timestamp != int64(i)*60000 {
+			t.Errorf("bar %d at %d, want ordered by timestamp", i, b.Timestamp)
+		}
+		if want := map[bool]float64{true: 9, false: 1.5}[i >= 2]; b.Close != want {
+			t.Errorf("bar %d close = %g, want %g (new bars win)", i, b.Close, want)
+		}
+	}
+}
+
+func TestActionsRoundTrip(t *testing.T) {
+	actions := []model.CorporateAction{
+		{ID: "s1", Type: model.ActionSplit, Ticker: "AAPL", Date: "2020-08-31", SplitFrom: 1, SplitTo: 4},
+		{ID: "d1", Type: model.ActionDividend, Ticker: "AAPL", Date: "2024-05-10", CashAmount: 0.25, Currency: "USD",
+			DividendType: "CD", Frequency: 4, DeclarationDate: "2024-05-02", RecordDate: "2024-05-13", PayDate: "2024-05-16"},
+	}
+	for _, ps := range []PacketSaver{CSVSaver{}, JSONSaver{}, ParquetSaver{}} {
+		path := filepath.Join(t.TempDir(), "AAPL_actions."+ps.Extension())
+		if err := ps.SaveActions(actions, path); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ps.LoadActions(path)
+		if err != nil {
+			t.Fatalf("%s: %v", ps.Extension(), err)
+		}
+		if len(got) != len(actions) || got[0] != actions[0] || got[1] != actions[1] {
+			t.Errorf("%s: round trip = %+v, want %+v", ps.Extension(), got, actions)
+		}
+	}
+}
+
+func TestMergeActions(t *testing.T) {
+	saved := []model.CorporateAction{
+		{ID: "d1", Type: model.ActionDividend, Date: "2024-02-09", CashAmount: 0.24},
+		{ID: "d2", Type: model.ActionDividend, Date: "2024-05-10", CashAmount: 0.24},
+	}
+	fresh := []model.CorporateAction{
+		{ID: "d2", Type: model.ActionDividend, Date: "2024-05-10", CashAmount: 0.25}, // corrected amount
+		{ID: "s1", Type: model.ActionSplit, Date: "2024-03-01", SplitFrom: 1, SplitTo: 2},
+	}
+	got := MergeActions(saved, fresh)
+	if len(got) != 3 {
+		t.Fatalf("merged = %+v, want 3 actions", got)
+	}
+	for i, want := range []string{"d1", "s1", "d2"} {
+		if got[i].ID != want {
+			t.Errorf("merged[%d] = %s, want %s (date order)", i, got[i].ID, want)
+		}
+	}
+	if got[2].CashAmount != 0.25 {
+		t.Errorf("saved action not replaced: %+v", got[2])
+	}
+}
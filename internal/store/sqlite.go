@@ -0,0 +1,117 @@
+// Package store holds embedded database backends for crawl state.
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite" // pure-Go SQLite driver, no cgo
+)
+
+// SQLiteProgressStore is a crawl.ProgressStore backed by an embedded SQLite
+// database. Each Put is one transaction, so updating a single ticker costs
+// O(batch) instead of rewriting every entry like the JSON file does.
+type SQLiteProgressStore struct {
+	db   *sql.DB
+	path string
+}
+
+const progressSchema = `
+CREATE TABLE IF NOT EXISTS progress (
+	key        TEXT PRIMARY KEY,
+	last_day   TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);`
+
+// NewSQLiteProgressStore opens (creating if needed) the database at path.
+func NewSQLiteProgressStore(path string) (*SQLiteProgressStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create progress db dir: %w", err)
+	}
+	dsn := fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)&_pragma=busy_timeout(5000)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open progress db %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1) // single writer; avoids SQLITE_BUSY between pooled conns
+	if _, err := db.Exec(progressSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init progress db %s: %w", path, err)
+	}
+	return &SQLiteProgressStore{db: db, path: path}, nil
+}
+
+// Path returns the database file path.
+func (s *SQLiteProgressStore) Path() string { return s.path }
+
+// Load returns all entries.
+func (s *SQLiteProgressStore) Load() (map[string]string, error) {
+	rows, err := s.db.Query(`SELECT key, last_day FROM progress`)
+	if err != nil {
+		return nil, fmt.Errorf("load progress: %w", err)
+	}
+	defer rows.Close()
+	m := make(map[string]string)
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, fmt.Errorf("scan progress: %w", err)
+		}
+		m[k] = v
+	}
+	return m, rows.Err()
+}
+
+// Put upserts, in a single transaction, the entries that advance coverage
+// (see crawl.ProgressStore): #earliest dates only move back, every other
+// date only forward.
+func (s *SQLiteProgressStore) Put(entries map[string]string) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`INSERT INTO progress (key, last_day, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET last_day = excluded.last_day, updated_at = excluded.updated_at
+		WHERE last_day NOT GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]'
+			OR (key LIKE '%#earliest' AND excluded.last_day < last_day)
+			OR (key NOT LIKE '%#earliest' AND excluded.last_day > last_day)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	now := time.Now().UTC().Format(time.RFC3339)
+	for k, v := range entries {
+		if _, err := stmt.Exec(k, v, now); err != nil {
+			return fmt.Errorf("upsert progress %s: %w", k, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete removes keys in a single transaction.
+func (s *SQLiteProgressStore) Delete(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, k := range keys {
+		if _, err := tx.Exec(`DELETE FROM progress WHERE key = ?`, k); err != nil {
+			return fmt.Errorf("delete progress %s: %w", k, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Close closes the database.
+func (s *SQLiteProgressStore) Close() error { return s.db.Close() }
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"us-data/internal/crawl"
+)
+
+func TestMigrateJSONToSQLite(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, ".lastday.json")
+	legacy := `{"AAPL": "2025-01-10", "massive:crypto:X:BTCUSD": "2025-01-12", "massive:stocks:MSFT": "2025-01-09"}`
+	if err := os.WriteFile(jsonPath, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := NewSQLiteProgressStore(filepath.Join(dir, ".progress.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// An existing, newer entry must win over the migrated one.
+	if err := db.Put(map[string]string{"massive:stocks:MSFT": "2025-02-01"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := crawl.MigrateProgress(crawl.NewJSONProgressStore(jsonPath), db, "massive"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"massive:stocks:AAPL":     "2025-01-10",
+		"massive:crypto:X:BTCUSD": "2025-01-12",
+		"massive:stocks:MSFT":     "2025-02-01",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if _, ok := got["AAPL"]; ok {
+		t.Errorf("legacy key should have been rewritten")
+	}
+}
+
+func TestPutNeverShrinksCoverage(t *testing.T) {
+	db, err := NewSQLiteProgressStore(filepath.Join(t.TempDir(), ".progress.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Put(map[string]string{
+		"massive:stocks:AAPL@5min":          "2025-06-10",
+		"massive:stocks:AAPL@5min#earliest": "2023-06-01",
+		"massive:splits@checked":            "garbage",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(map[string]string{
+		"massive:stocks:AAPL@5min":          "2025-06-05", // older: dropped
+		"massive:stocks:AAPL@5min#earliest": "2024-01-02", // newer earliest: dropped
+		"massive:splits@checked":            "2025-06-01", // replaces a non-date
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(map[string]string{"massive:stocks:AAPL@5min#earliest": "2022-01-03"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"massive:stocks:AAPL@5min":          "2025-06-10",
+		"massive:stocks:AAPL@5min#earliest": "2022-01-03",
+		"massive:splits@checked":            "2025-06-01",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
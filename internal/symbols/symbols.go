@@ -0,0 +1,216 @@
+// Package symbols tracks ticker symbol changes (FB → META), so a renamed
+// security keeps its progress and history instead of being backfilled from
+// scratch under its new symbol while the old one is orphaned.
+//
+// Changes come from the provider's ticker events or from a local mapping
+// file. Each applied change is kept in a log (.symbols.json), and the ticker
+// directories of one security are linked under its stable id:
+//
+//	stocks/.by-id/BBG000MM2P62/FB   → ../../FB
+//	stocks/.by-id/BBG000MM2P62/META → ../../META
+//
+// The id is the composite FIGI when known, otherwise the old symbol.
+package symbols
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"us-data/internal/fsutil"
+)
+
+// Sources of a Change.
+const (
+	FromEvents  = "events"  // provider ticker events
+	FromMapping = "mapping" // local mapping file
+)
+
+// byIDDir is the directory, under a class directory, that links the ticker
+// directories of one security under its stable id.
+const byIDDir = ".by-id"
+
+// Change is one symbol change of a security: it trades as To from Date on,
+// as From before.
+type Change struct {
+	Class  string `json:"class,omitempty"` // empty in the mapping file: any class
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Date   string `json:"date,omitempty"` // YYYY-MM-DD, first day under To
+	ID     string `json:"id,omitempty"`   // composite FIGI when known
+	Source string `json:"source,omitempty"`
+
+	Applied  time.Time `json:"applied"`
+	Migrated int       `json:"migrated_keys,omitempty"` // progress entries moved to To
+}
+
+// StableID returns c's id, or its old symbol when no id is known.
+func (c Change) StableID() string {
+	if c.ID != "" {
+		return c.ID
+	}
+	return c.From
+}
+
+// LoadMapping reads the local mapping file: a JSON array of changes, e.g.
+//
+//	[{"from": "FB", "to": "META", "date": "2022-06-09"}]
+//
+// A missing file or an empty path is an empty mapping.
+func LoadMapping(path string) ([]Change, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Change
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i := range out {
+		c := &out[i]
+		c.Class = strings.ToLower(strings.TrimSpace(c.Class))
+		c.From = strings.ToUpper(strings.TrimSpace(c.From))
+		c.To = strings.ToUpper(strings.TrimSpace(c.To))
+		c.Source = FromMapping
+		if c.From == "" || c.To == "" || c.From == c.To {
+			return nil, fmt.Errorf("%s: entry %d: from and to must be two different symbols", path, i)
+		}
+	}
+	return out, nil
+}
+
+// Log is the change history (.symbols.json), in application order.
+// Not safe for concurrent use.
+type Log struct {
+	path string
+
+	Changes []Change `json:"changes"`
+
+	// Held lists, per class, the new tickers whose change lookup failed and
+	// is retried every cycle; they are not crawled until it succeeds.
+	Held map[string][]string `json:"held,omitempty"`
+}
+
+// OpenLog loads the log at path; a missing file is an empty log.
+func OpenLog(path string) (*Log, error) {
+	l := &Log{path: path}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, l); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+	return l, nil
+}
+
+// Has reports whether a change from → to of class is already recorded.
+func (l *Log) Has(class, from, to string) bool {
+	return slices.ContainsFunc(l.Changes, func(c Change) bool {
+		return c.Class == class && c.From == from && c.To == to
+	})
+}
+
+// Record appends c unless the same change is already recorded.
+func (l *Log) Record(c Change) {
+	if !l.Has(c.Class, c.From, c.To) {
+		l.Changes = append(l.Changes, c)
+	}
+}
+
+// RenamedAway reports whether ticker of class was renamed to another symbol
+// and has not become a current symbol again since.
+func (l *Log) RenamedAway(class, ticker string) bool {
+	away := false
+	for _, c := range l.Changes { // in application order
+		if c.Class != class {
+			continue
+		}
+		switch ticker {
+		case c.From:
+			away = true
+		case c.To:
+			away = false
+		}
+	}
+	return away
+}
+
+// Hold marks ticker of class as held back; it reports whether it was not
+// held yet.
+func (l *Log) Hold(class, ticker string) bool {
+	if l.IsHeld(class, ticker) {
+		return false
+	}
+	if l.Held == nil {
+		l.Held = make(map[string][]string)
+	}
+	l.Held[class] = append(l.Held[class], ticker)
+	return true
+}
+
+// Release removes ticker of class from the held tickers; it reports whether
+// it was held.
+func (l *Log) Release(class, ticker string) bool {
+	i := slices.Index(l.Held[class], ticker)
+	if i < 0 {
+		return false
+	}
+	l.Held[class] = slices.Delete(l.Held[class], i, i+1)
+	if len(l.Held[class]) == 0 {
+		delete(l.Held, class)
+	}
+	return true
+}
+
+// IsHeld reports whether ticker of class is held back.
+func (l *Log) IsHeld(class, ticker string) bool {
+	return slices.Contains(l.Held[class], ticker)
+}
+
+// Save writes the log atomically.
+func (l *Log) Save() error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(l.path, data, 0o644)
+}
+
+// Link makes the existing ticker directories dirs under classDir (named as
+// by the output layout, e.g. META or ticker=META) reachable as
+// classDir/.by-id/<id>/<dir> (relative symlinks). Missing directories are
+// skipped; existing links are left as they are.
+func Link(classDir, id string, dirs ...string) error {
+	idDir := filepath.Join(classDir, byIDDir, id)
+	var errs []error
+	for _, t := range dirs {
+		if _, err := os.Stat(filepath.Join(classDir, t)); err != nil {
+			continue
+		}
+		if err := os.MkdirAll(idDir, 0o755); err != nil {
+			return err
+		}
+		link := filepath.Join(idDir, t)
+		err := os.Symlink(filepath.Join("..", "..", t), link)
+		if err != nil && !errors.Is(err, fs.ErrExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,104 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLogRecordsChangesAndRenamedAway(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".symbols.json")
+	l, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fb := Change{Class: "stocks", From: "FB", To: "META", Date: "2022-06-09", ID: "BBG000MM2P62", Applied: time.Now().UTC()}
+	l.Record(fb)
+	l.Record(fb) // recorded once
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Changes) != 1 || !l.Has("stocks", "FB", "META") {
+		t.Fatalf("changes = %+v, want FB → META once", l.Changes)
+	}
+	if !l.RenamedAway("stocks", "FB") || l.RenamedAway("stocks", "META") || l.RenamedAway("crypto", "FB") {
+		t.Error("only stocks FB should be renamed away")
+	}
+
+	// A symbol that becomes current again (renamed back) is crawled again.
+	l.Record(Change{Class: "stocks", From: "META", To: "FB"})
+	if l.RenamedAway("stocks", "FB") || !l.RenamedAway("stocks", "META") {
+		t.Error("after META → FB, only META should be renamed away")
+	}
+}
+
+func TestLoadMappingAndLink(t *testing.T) {
+	dir := t.TempDir()
+	mapPath := filepath.Join(dir, "symbols.json")
+	if err := os.WriteFile(mapPath, []byte(`[{"from": " fb", "to": "Meta", "date": "2022-06-09"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadMapping(mapPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m[0].From != "FB" || m[0].To != "META" || m[0].Source != FromMapping || m[0].StableID() != "FB" {
+		t.Fatalf("mapping = %+v", m)
+	}
+	if m, err := LoadMapping(filepath.Join(dir, "missing.json")); err != nil || m != nil {
+		t.Errorf("missing file: %v, %v; want empty mapping", m, err)
+	}
+
+	classDir := filepath.Join(dir, "stocks")
+	if err := os.MkdirAll(filepath.Join(classDir, "FB"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(classDir, "FB", "FB_5min_2022-06-01.parquet"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 { // idempotent
+		if err := Link(classDir, "BBG000MM2P62", "FB", "META"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(classDir, byIDDir, "BBG000MM2P62", "FB", "FB_5min_2022-06-01.parquet")); err != nil {
+		t.Errorf("FB not reachable by id: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(classDir, byIDDir, "BBG000MM2P62", "META")); err == nil {
+		t.Error("META has no directory yet and must not be linked")
+	}
+}
+
+func TestLogHoldsTickersAcrossReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".symbols.json")
+	l, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.Hold("stocks", "META") || l.Hold("stocks", "META") || !l.Hold("stocks", "XYZ") {
+		t.Fatal("Hold must report only newly held tickers")
+	}
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.IsHeld("stocks", "META") || l.IsHeld("crypto", "META") {
+		t.Errorf("held = %v, want stocks META", l.Held)
+	}
+	if !l.Release("stocks", "META") || l.Release("stocks", "META") || !l.Release("stocks", "XYZ") {
+		t.Error("Release must report only held tickers")
+	}
+	if len(l.Held) != 0 {
+		t.Errorf("held = %v, want none", l.Held)
+	}
+}